@@ -29,12 +29,22 @@ func main() {
 	}
 	defer store.Close()
 
+	// Messages still in progress were interrupted by a crash or forced quit
+	if n, err := store.RecoverInterruptedMessages(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to recover interrupted messages: %v\n", err)
+	} else if n > 0 {
+		fmt.Printf("Recovered %d interrupted message(s)\n", n)
+	}
+
 	// Initialize agent service
 	agentService, err := agent.NewService(cfg, store)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize agent service: %v\n", err)
 		os.Exit(1)
 	}
+	// Deferred after the store so replies still running are saved before
+	// the database closes.
+	defer agentService.Close()
 
 	// Create Fyne app with Vercel theme
 	a := app.New()
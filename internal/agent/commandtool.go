@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"axe-desktop/internal/workspace"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const toolRunCommand = "run_command"
+
+// alwaysAskTools need the user's approval for every call; an allow policy or
+// a remembered answer does not apply to them.
+var alwaysAskTools = map[string]bool{
+	toolRunCommand: true,
+}
+
+type runCommandArgs struct {
+	Command        string `json:"command" jsonschema:"Shell command to run, such as go test ./..."`
+	Dir            string `json:"dir,omitempty" jsonschema:"Folder to run in, relative to the workspace root; empty for the root"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" jsonschema:"Seconds before the command is stopped; defaults to 120, at most 600"`
+}
+
+// ToolOutputHandler receives the output of a running tool call as it
+// arrives. toolCallID is the ID of the stored tool call.
+type ToolOutputHandler func(sessionID, toolCallID, output string)
+
+// SetToolOutputHandler sets where the output of running commands is sent.
+func (s *Service) SetToolOutputHandler(handler ToolOutputHandler) {
+	s.mu.Lock()
+	s.onToolOutput = handler
+	s.mu.Unlock()
+}
+
+// commandTool builds the run_command tool, which runs shell commands in the
+// session's workspace.
+func (s *Service) commandTool() (tool.Tool, error) {
+	return functiontool.New(functiontool.Config{
+		Name: toolRunCommand,
+		Description: fmt.Sprintf("Run a shell command in the workspace and return its exit code and combined output (at most 64 KB). "+
+			"Only a few environment variables such as PATH and HOME are passed on. The user approves each command before it runs. "+
+			"Commands are stopped after %d seconds unless timeout_seconds says otherwise.", int(workspace.DefaultCommandTimeout.Seconds())),
+	}, func(ctx tool.Context, args runCommandArgs) (*workspace.CommandResult, error) {
+		ws, err := s.workspace(ctx.SessionID())
+		if err != nil {
+			return nil, err
+		}
+		timeout := time.Duration(args.TimeoutSeconds) * time.Second
+		return ws.Run(ctx, args.Command, args.Dir, timeout, s.toolOutput(ctx.SessionID(), ctx.FunctionCallID()))
+	})
+}
+
+// toolOutput returns the function that forwards a running call's output to
+// the output handler, or nil if there is nowhere to send it.
+func (s *Service) toolOutput(sessionID, callID string) func(string) {
+	s.mu.RLock()
+	handler := s.onToolOutput
+	gen := s.generations[sessionID]
+	s.mu.RUnlock()
+	if handler == nil || gen == nil || gen.toolCalls == nil {
+		return nil
+	}
+	toolCallID := gen.toolCalls.recordedID(callID)
+	if toolCallID == "" {
+		return nil
+	}
+	return func(output string) {
+		handler(sessionID, toolCallID, output)
+	}
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"axe-desktop/pkg/models"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+const (
+	// compactThreshold is the share of the context window at which the
+	// conversation is summarized.
+	compactThreshold = 0.8
+	compactTimeout   = 2 * time.Minute
+
+	// maxCompactMessageRunes caps each message in the compaction prompt, and
+	// the prompt as a whole is kept to roughly half the context window,
+	// assuming four characters per token.
+	maxCompactMessageRunes = 4000
+	runesPerToken          = 4
+)
+
+// summaryPreamble introduces the context summary in the instruction.
+const summaryPreamble = "Summary of the earlier conversation, which is no longer shown in full:\n\n"
+
+const compactInstruction = `You condense a chat conversation so it can continue without the full history. Start with a one-sentence overview on its own line. Then list the user's goals, decisions and conclusions reached, important facts, names, file paths and code, and any open questions or unfinished tasks. Keep details that later turns are likely to need; drop pleasantries and repetition. Write in the language of the conversation.`
+
+// tokenUsage accumulates the usage reported for each model call in a turn.
+type tokenUsage struct {
+	prompt int
+	output int
+	// context is the size of the last call, request plus reply.
+	context int
+}
+
+func (u *tokenUsage) add(meta *genai.GenerateContentResponseUsageMetadata) {
+	if meta == nil {
+		return
+	}
+	prompt := int(meta.PromptTokenCount)
+	output := int(meta.CandidatesTokenCount) + int(meta.ThoughtsTokenCount)
+	u.prompt += prompt
+	u.output += output
+	u.context = int(meta.TotalTokenCount)
+	if u.context == 0 {
+		u.context = prompt + output
+	}
+}
+
+// record stores the usage on the assistant message: the total in TokenCount
+// and the breakdown in its metadata.
+func (u *tokenUsage) record(msg *models.Message) {
+	if u.prompt == 0 && u.output == 0 {
+		return
+	}
+	total := u.prompt + u.output
+	msg.TokenCount = &total
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]any{}
+	}
+	msg.Metadata["prompt_tokens"] = u.prompt
+	msg.Metadata["output_tokens"] = u.output
+	msg.Metadata["context_tokens"] = u.context
+}
+
+// compactIfNeeded summarizes the conversation once the latest exchange fills
+// most of the model's context window.
+func (s *Service) compactIfNeeded(sessionID string, contextTokens int) {
+	if contextTokens == 0 {
+		return
+	}
+	cfg, err := s.resolveRunnerConfig(sessionID)
+	if err != nil {
+		return
+	}
+	limit := cfg.provider.ContextLimit()
+	if float64(contextTokens) < float64(limit)*compactThreshold {
+		return
+	}
+
+	fmt.Printf("[Agent] session=%s context=%d/%d, summarizing history\n", sessionID, contextTokens, limit)
+	ctx, cancel := context.WithTimeout(context.Background(), compactTimeout)
+	defer cancel()
+	if err := s.compact(ctx, sessionID, limit); err != nil {
+		fmt.Printf("[Agent] failed to summarize history: %v\n", err)
+	}
+}
+
+// compact replaces the conversation so far with a summary. From the next turn
+// the summary is sent in the instruction and only later messages are replayed
+// as history.
+func (s *Service) compact(ctx context.Context, sessionID string, contextLimit int) error {
+	sess, err := s.storage.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	messages, err := s.storage.ListMessages(sessionID, 0, 0)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	lines := transcriptLines(messages, sess.SummaryUntil, maxCompactMessageRunes)
+	if len(lines) == 0 {
+		return nil
+	}
+	budget := contextLimit * runesPerToken / 2
+	for len(lines) > 1 && transcriptRunes(lines) > budget {
+		lines = lines[1:]
+	}
+
+	var prompt strings.Builder
+	if sess.SummaryUntil != nil && sess.ContextSummary != nil {
+		prompt.WriteString(summaryPreamble)
+		prompt.WriteString(*sess.ContextSummary)
+		prompt.WriteString("\n\nConversation since then:\n\n")
+	}
+	prompt.WriteString(strings.Join(lines, "\n\n"))
+
+	summary, err := s.complete(ctx, sessionID, compactInstruction, prompt.String())
+	if err != nil {
+		return err
+	}
+	if summary == "" {
+		return fmt.Errorf("model returned an empty summary")
+	}
+
+	if err := s.storage.SetContextSummary(sessionID, summary, messages[0].CreatedAt); err != nil {
+		return err
+	}
+
+	// Drop the agent's event history; ensureSession rebuilds it from the
+	// messages after the summary on the next turn.
+	return s.sessionService.Delete(ctx, &session.DeleteRequest{
+		AppName:   appName,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+}
+
+func transcriptRunes(lines []string) int {
+	n := 0
+	for _, line := range lines {
+		n += len([]rune(line))
+	}
+	return n
+}
@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"axe-desktop/internal/workspace"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+const (
+	toolReadFile    = "read_file"
+	toolListDir     = "list_dir"
+	toolSearchFiles = "search_files"
+	toolWriteFile   = "write_file"
+	toolApplyPatch  = "apply_patch"
+)
+
+// readOnlyTools run without approval unless a policy says otherwise; the
+// file tools that write always go through the approval flow by default.
+var readOnlyTools = map[string]bool{
+	toolReadFile:    true,
+	toolListDir:     true,
+	toolSearchFiles: true,
+}
+
+// workspaceInstruction tells the model about the workspace tools.
+const workspaceInstruction = "You can work with the files of the project in %s using the read_file, list_dir, search_files, write_file and apply_patch tools, and run shell commands there with run_command. Paths are relative to that folder, and files outside it cannot be accessed. Read a file before changing it, and prefer apply_patch for small edits. The user approves every write and command, so explain what a command is for before running it."
+
+type readFileArgs struct {
+	Path   string `json:"path" jsonschema:"File path relative to the workspace root"`
+	Offset int    `json:"offset,omitempty" jsonschema:"First line to read, starting at 1"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"Maximum number of lines to read, at most 2000"`
+}
+
+type listDirArgs struct {
+	Path string `json:"path,omitempty" jsonschema:"Folder path relative to the workspace root; empty for the root"`
+}
+
+type listDirResult struct {
+	Path      string            `json:"path"`
+	Entries   []workspace.Entry `json:"entries"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+type searchFilesArgs struct {
+	Pattern string `json:"pattern" jsonschema:"Regular expression (RE2 syntax) matched against each line"`
+	Path    string `json:"path,omitempty" jsonschema:"Folder to search, relative to the workspace root; empty for the root"`
+	Glob    string `json:"glob,omitempty" jsonschema:"Only search files whose name matches this glob, such as *.go"`
+}
+
+type searchFilesResult struct {
+	Matches   []workspace.Match `json:"matches"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+type writeFileArgs struct {
+	Path    string `json:"path" jsonschema:"File path relative to the workspace root"`
+	Content string `json:"content" jsonschema:"The complete new content of the file"`
+}
+
+type applyPatchArgs struct {
+	Path  string `json:"path" jsonschema:"File path relative to the workspace root"`
+	Patch string `json:"patch" jsonschema:"Unified diff for this file with @@ hunk headers and a few lines of context around each change"`
+}
+
+type writeResult struct {
+	Path    string `json:"path"`
+	Created bool   `json:"created,omitempty"`
+	Lines   int    `json:"lines"`
+}
+
+// workspace opens the workspace folder of a session.
+func (s *Service) workspace(sessionID string) (*workspace.Workspace, error) {
+	sess, err := s.storage.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if sess.Workspace == "" {
+		return nil, fmt.Errorf("no workspace folder is set for this session")
+	}
+	return workspace.New(sess.Workspace)
+}
+
+// fileTools builds the filesystem tools. They look up the workspace of the
+// calling session on each call.
+func (s *Service) fileTools() ([]tool.Tool, error) {
+	readFile, err := functiontool.New(functiontool.Config{
+		Name:        toolReadFile,
+		Description: "Read a text file from the workspace. Long files are returned in ranges of lines; use offset and limit to read further.",
+	}, func(ctx tool.Context, args readFileArgs) (*workspace.FileContent, error) {
+		ws, err := s.workspace(ctx.SessionID())
+		if err != nil {
+			return nil, err
+		}
+		return ws.ReadFile(args.Path, args.Offset, args.Limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listDir, err := functiontool.New(functiontool.Config{
+		Name:        toolListDir,
+		Description: "List the files and folders in a workspace folder.",
+	}, func(ctx tool.Context, args listDirArgs) (*listDirResult, error) {
+		ws, err := s.workspace(ctx.SessionID())
+		if err != nil {
+			return nil, err
+		}
+		entries, truncated, err := ws.ListDir(args.Path)
+		if err != nil {
+			return nil, err
+		}
+		path := args.Path
+		if path == "" {
+			path = "."
+		}
+		return &listDirResult{Path: path, Entries: entries, Truncated: truncated}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	searchFiles, err := functiontool.New(functiontool.Config{
+		Name:        toolSearchFiles,
+		Description: fmt.Sprintf("Search the text files in the workspace for lines matching a regular expression. Returns at most %d matches.", workspace.MaxSearchMatches),
+	}, func(ctx tool.Context, args searchFilesArgs) (*searchFilesResult, error) {
+		ws, err := s.workspace(ctx.SessionID())
+		if err != nil {
+			return nil, err
+		}
+		matches, truncated, err := ws.Search(args.Pattern, args.Path, args.Glob)
+		if err != nil {
+			return nil, err
+		}
+		return &searchFilesResult{Matches: matches, Truncated: truncated}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	writeFile, err := functiontool.New(functiontool.Config{
+		Name:        toolWriteFile,
+		Description: "Create a file in the workspace or replace its whole content. The user reviews the change before it is written.",
+	}, func(ctx tool.Context, args writeFileArgs) (*writeResult, error) {
+		ws, err := s.workspace(ctx.SessionID())
+		if err != nil {
+			return nil, err
+		}
+		_, exists, err := ws.Current(args.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := ws.WriteFile(args.Path, args.Content); err != nil {
+			return nil, err
+		}
+		return &writeResult{Path: args.Path, Created: !exists, Lines: countLines(args.Content)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	applyPatch, err := functiontool.New(functiontool.Config{
+		Name:        toolApplyPatch,
+		Description: "Edit a file in the workspace by applying a unified diff. The user reviews the change before it is written.",
+	}, func(ctx tool.Context, args applyPatchArgs) (*writeResult, error) {
+		ws, err := s.workspace(ctx.SessionID())
+		if err != nil {
+			return nil, err
+		}
+		content, err := patched(ws, args.Path, args.Patch)
+		if err != nil {
+			return nil, err
+		}
+		if err := ws.WriteFile(args.Path, content); err != nil {
+			return nil, err
+		}
+		return &writeResult{Path: args.Path, Lines: countLines(content)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []tool.Tool{readFile, listDir, searchFiles, writeFile, applyPatch}, nil
+}
+
+// patched returns a file's content with a patch applied.
+func patched(ws *workspace.Workspace, path, patch string) (string, error) {
+	current, exists, err := ws.Current(path)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("%s does not exist; use write_file to create it", path)
+	}
+	return workspace.ApplyPatch(current, patch)
+}
+
+// writePreview returns the diff a write tool call would make, for the user
+// to review before approving it. It returns "" for other tools.
+func (s *Service) writePreview(sessionID, toolName string, args map[string]any) (string, error) {
+	if toolName != toolWriteFile && toolName != toolApplyPatch {
+		return "", nil
+	}
+	ws, err := s.workspace(sessionID)
+	if err != nil {
+		return "", err
+	}
+	path, _ := args["path"].(string)
+	full, err := ws.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	current, _, err := ws.Current(path)
+	if err != nil {
+		return "", err
+	}
+
+	var content string
+	if toolName == toolWriteFile {
+		content, _ = args["content"].(string)
+	} else {
+		patch, _ := args["patch"].(string)
+		if content, err = patched(ws, path, patch); err != nil {
+			return "", err
+		}
+	}
+	diff := workspace.Diff(ws.Rel(full), current, content)
+	if diff == "" {
+		diff = "(no changes)"
+	}
+	return diff, nil
+}
+
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"axe-desktop/pkg/models"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/mcptoolset"
+)
+
+const (
+	stdioMaxRestarts    = 5
+	stdioRestartWindow  = time.Minute
+	stdioTerminateDelay = 3 * time.Second
+)
+
+// stdioServer launches an MCP server as a child process. Every Connect starts a
+// fresh process, so the mcptoolset connection refresher restarts crashed
+// servers transparently on the next tool call. A single toolset is shared by
+// all runners so only one process per server is alive at a time.
+type stdioServer struct {
+	cfg     models.MCPServer
+	logDir  string
+	toolset tool.Toolset
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	logFile  *os.File
+	restarts []time.Time
+	started  bool
+}
+
+func newStdioServer(cfg models.MCPServer, logDir string) *stdioServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &stdioServer{
+		cfg:    cfg,
+		logDir: logDir,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (s *stdioServer) Connect(ctx context.Context) (mcp.Connection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return nil, fmt.Errorf("mcp server %s is stopped", s.cfg.Name)
+	}
+
+	if err := s.openLog(); err != nil {
+		return nil, err
+	}
+
+	if s.started {
+		now := time.Now()
+		recent := s.restarts[:0]
+		for _, t := range s.restarts {
+			if now.Sub(t) < stdioRestartWindow {
+				recent = append(recent, t)
+			}
+		}
+		s.restarts = append(recent, now)
+		if len(s.restarts) > stdioMaxRestarts {
+			return nil, fmt.Errorf("mcp server %s restarted too often, giving up", s.cfg.Name)
+		}
+		s.logf("restarting (exit: %v)", s.exitState())
+	}
+
+	cmd := exec.CommandContext(s.ctx, s.cfg.Command, s.cfg.Args...)
+	cmd.Stderr = s.logFile
+	cmd.WaitDelay = stdioTerminateDelay
+	cmd.Env = os.Environ()
+	for k, v := range s.cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	transport := &mcp.CommandTransport{
+		Command:           cmd,
+		TerminateDuration: stdioTerminateDelay,
+	}
+	conn, err := transport.Connect(ctx)
+	if err != nil {
+		s.logf("failed to start: %v", err)
+		return nil, fmt.Errorf("failed to start mcp server %s: %w", s.cfg.Name, err)
+	}
+
+	s.cmd = cmd
+	s.started = true
+	s.logf("started pid=%d", cmd.Process.Pid)
+	return conn, nil
+}
+
+// Stop kills the child process and prevents further restarts.
+func (s *stdioServer) Stop() {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != nil && s.cmd.Process != nil {
+		s.logf("stopping pid=%d", s.cmd.Process.Pid)
+		_ = s.cmd.Process.Kill()
+	}
+	if s.logFile != nil {
+		s.logFile.Close()
+		s.logFile = nil
+	}
+}
+
+func (s *stdioServer) openLog() error {
+	if s.logFile != nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create log dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(s.logDir, "mcp-"+s.cfg.ID+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open mcp log: %w", err)
+	}
+	s.logFile = f
+	return nil
+}
+
+func (s *stdioServer) logf(format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	fmt.Printf("[MCP] %s: %s\n", s.cfg.Name, line)
+	if s.logFile != nil {
+		fmt.Fprintf(s.logFile, "%s [axe-desktop] %s\n", time.Now().Format(time.RFC3339), line)
+	}
+}
+
+func (s *stdioServer) exitState() string {
+	if s.cmd == nil || s.cmd.ProcessState == nil {
+		return "unknown"
+	}
+	return s.cmd.ProcessState.String()
+}
+
+func (s *Service) mcpToolsets() []tool.Toolset {
+	var toolsets []tool.Toolset
+	active := make(map[string]bool)
+	for _, mcpSrv := range s.config.MCPServers {
+		if !mcpSrv.Enabled {
+			continue
+		}
+
+		var transport mcp.Transport
+		switch mcpSrv.Type {
+		case models.MCPServerHTTP:
+			transport = &mcp.StreamableClientTransport{
+				Endpoint: mcpSrv.URL,
+			}
+		case models.MCPServerStdio:
+			active[mcpSrv.ID] = true
+			if srv, err := s.stdioServer(mcpSrv); err == nil {
+				toolsets = append(toolsets, srv.toolset)
+			} else {
+				fmt.Printf("[MCP] %s: %v\n", mcpSrv.Name, err)
+			}
+			continue
+		default:
+			continue
+		}
+
+		toolset, err := mcptoolset.New(mcptoolset.Config{
+			Transport: transport,
+		})
+		if err == nil {
+			toolsets = append(toolsets, toolset)
+		}
+	}
+	s.pruneStdioServers(active)
+	return toolsets
+}
+
+func (s *Service) stdioServer(cfg models.MCPServer) (*stdioServer, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("stdio server has no command")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if srv, ok := s.stdioServers[cfg.ID]; ok {
+		if sameStdioServer(srv.cfg, cfg) {
+			return srv, nil
+		}
+		// The server was edited: replace its process, and drop the runners
+		// still holding the old toolset so they are rebuilt.
+		srv.Stop()
+		delete(s.stdioServers, cfg.ID)
+		clear(s.runners)
+	}
+
+	srv := newStdioServer(cfg, s.config.LogDir)
+	toolset, err := mcptoolset.New(mcptoolset.Config{
+		Transport: srv,
+	})
+	if err != nil {
+		return nil, err
+	}
+	srv.toolset = toolset
+	s.stdioServers[cfg.ID] = srv
+	return srv, nil
+}
+
+// pruneStdioServers stops the servers that were removed or disabled.
+func (s *Service) pruneStdioServers(active map[string]bool) {
+	s.mu.Lock()
+	var removed []*stdioServer
+	for id, srv := range s.stdioServers {
+		if !active[id] {
+			removed = append(removed, srv)
+			delete(s.stdioServers, id)
+		}
+	}
+	if len(removed) > 0 {
+		clear(s.runners)
+	}
+	s.mu.Unlock()
+
+	for _, srv := range removed {
+		srv.Stop()
+	}
+}
+
+// sameStdioServer reports whether two configurations start the same process.
+func sameStdioServer(a, b models.MCPServer) bool {
+	return a.Name == b.Name && a.Command == b.Command &&
+		slices.Equal(a.Args, b.Args) && maps.Equal(a.Env, b.Env)
+}
+
+func (s *Service) stopStdioServers() {
+	s.mu.Lock()
+	servers := s.stdioServers
+	s.stdioServers = make(map[string]*stdioServer)
+	s.mu.Unlock()
+
+	for _, srv := range servers {
+		srv.Stop()
+	}
+}
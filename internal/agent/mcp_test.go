@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"testing"
+
+	"axe-desktop/internal/config"
+	"axe-desktop/pkg/models"
+)
+
+func TestStdioServersFollowSettings(t *testing.T) {
+	cfg := &config.Config{
+		LogDir: t.TempDir(),
+		MCPServers: []models.MCPServer{{
+			ID: "files", Name: "Files", Type: models.MCPServerStdio,
+			Command: "mcp-files", Args: []string{"--root", "/a"}, Enabled: true,
+		}},
+	}
+	s := &Service{
+		config:       cfg,
+		runners:      make(map[string]*sessionRunner),
+		stdioServers: make(map[string]*stdioServer),
+	}
+
+	s.mcpToolsets()
+	first := s.stdioServers["files"]
+	if first == nil {
+		t.Fatal("stdio server was not created")
+	}
+	s.mcpToolsets()
+	if s.stdioServers["files"] != first {
+		t.Error("unchanged server was replaced")
+	}
+
+	cfg.MCPServers[0].Args = []string{"--root", "/b"}
+	s.runners["session"] = &sessionRunner{}
+	s.mcpToolsets()
+	second := s.stdioServers["files"]
+	if second == nil || second == first {
+		t.Fatal("edited server was not replaced")
+	}
+	if first.ctx.Err() == nil {
+		t.Error("old server was not stopped")
+	}
+	if len(s.runners) != 0 {
+		t.Error("runners holding the old toolset were kept")
+	}
+
+	cfg.MCPServers[0].Enabled = false
+	s.mcpToolsets()
+	if len(s.stdioServers) != 0 {
+		t.Error("disabled server was kept")
+	}
+	if second.ctx.Err() == nil {
+		t.Error("disabled server was not stopped")
+	}
+}
@@ -0,0 +1,636 @@
+// Package openai implements the ADK model.LLM interface for OpenAI-compatible
+// chat completions endpoints (OpenAI, llama.cpp, vLLM, Ollama, ...).
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// defaultClient bounds connecting and waiting for the response headers, so
+// an endpoint that stalls fails instead of hanging the reply. There is no
+// overall timeout as streamed replies can run for minutes.
+var defaultClient = newDefaultClient()
+
+func newDefaultClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	// Non-streaming requests only get headers once the whole reply is
+	// generated, which can take a while on local models.
+	transport.ResponseHeaderTimeout = 5 * time.Minute
+	return &http.Client{Transport: transport}
+}
+
+type Config struct {
+	APIKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+type openAIModel struct {
+	name    string
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewModel returns a model.LLM backed by an OpenAI-compatible chat completions API.
+func NewModel(modelName string, cfg Config) (model.LLM, error) {
+	if modelName == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = defaultClient
+	}
+
+	return &openAIModel{
+		name:    modelName,
+		apiKey:  cfg.APIKey,
+		baseURL: baseURL,
+		client:  client,
+	}, nil
+}
+
+func (m *openAIModel) Name() string {
+	return m.name
+}
+
+func (m *openAIModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	if stream {
+		return m.generateStream(ctx, req)
+	}
+
+	return func(yield func(*model.LLMResponse, error) bool) {
+		resp, err := m.generate(ctx, req)
+		yield(resp, err)
+	}
+}
+
+func (m *openAIModel) generate(ctx context.Context, req *model.LLMRequest) (*model.LLMResponse, error) {
+	payload, err := buildRequest(m.name, req, false)
+	if err != nil {
+		return nil, err
+	}
+	body, err := m.post(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp chatResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("openai: %s", resp.Error.Message)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	choice := resp.Choices[0]
+	llmResp := &model.LLMResponse{
+		Content:       responseContent(choice.Message.Content, choice.Message.ToolCalls),
+		UsageMetadata: resp.Usage.toGenai(),
+		FinishReason:  finishReason(choice.FinishReason),
+		TurnComplete:  true,
+	}
+	return llmResp, nil
+}
+
+func (m *openAIModel) generateStream(ctx context.Context, req *model.LLMRequest) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		payload, err := buildRequest(m.name, req, true)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		body, err := m.post(ctx, payload)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+
+		var text strings.Builder
+		var usage *chatUsage
+		var reason string
+		calls := make(map[int]*toolCall)
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				break
+			}
+
+			var chunk chatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				yield(nil, fmt.Errorf("failed to decode stream chunk: %w", err))
+				return
+			}
+			if chunk.Error != nil {
+				yield(nil, fmt.Errorf("openai: %s", chunk.Error.Message))
+				return
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+
+			choice := chunk.Choices[0]
+			if choice.FinishReason != "" {
+				reason = choice.FinishReason
+			}
+			for _, delta := range choice.Delta.ToolCalls {
+				call, ok := calls[delta.Index]
+				if !ok {
+					call = &toolCall{Type: "function"}
+					calls[delta.Index] = call
+				}
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				call.Function.Name += delta.Function.Name
+				call.Function.Arguments += delta.Function.Arguments
+			}
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				partial := &model.LLMResponse{
+					Content: genai.NewContentFromText(choice.Delta.Content, genai.RoleModel),
+					Partial: true,
+				}
+				if !yield(partial, nil) {
+					return
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, fmt.Errorf("failed to read stream: %w", err))
+			return
+		}
+
+		indexes := make([]int, 0, len(calls))
+		for idx := range calls {
+			indexes = append(indexes, idx)
+		}
+		sort.Ints(indexes)
+		toolCalls := make([]toolCall, 0, len(indexes))
+		for _, idx := range indexes {
+			toolCalls = append(toolCalls, *calls[idx])
+		}
+
+		yield(&model.LLMResponse{
+			Content:       responseContent(text.String(), toolCalls),
+			UsageMetadata: usage.toGenai(),
+			FinishReason:  finishReason(reason),
+			TurnComplete:  true,
+		}, nil)
+	}
+}
+
+func (m *openAIModel) post(ctx context.Context, payload *chatRequest) (io.ReadCloser, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if payload.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	if m.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call model: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiErr chatResponse
+		if json.Unmarshal(msg, &apiErr) == nil && apiErr.Error != nil {
+			return nil, fmt.Errorf("openai: %s (status %d)", apiErr.Error.Message, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("openai: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
+
+type chatRequest struct {
+	Model            string         `json:"model"`
+	Messages         []chatMessage  `json:"messages"`
+	Tools            []chatTool     `json:"tools,omitempty"`
+	Stream           bool           `json:"stream,omitempty"`
+	StreamOptions    *streamOptions `json:"stream_options,omitempty"`
+	Temperature      *float32       `json:"temperature,omitempty"`
+	TopP             *float32       `json:"top_p,omitempty"`
+	MaxTokens        int32          `json:"max_tokens,omitempty"`
+	Stop             []string       `json:"stop,omitempty"`
+	PresencePenalty  *float32       `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32       `json:"frequency_penalty,omitempty"`
+	Seed             *int32         `json:"seed,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role       string     `json:"role"`
+	Content    any        `json:"content,omitempty"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+	File     *fileData `json:"file,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type fileData struct {
+	Filename string `json:"filename"`
+	FileData string `json:"file_data"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type toolCall struct {
+	Index    int    `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string     `json:"content"`
+			ToolCalls []toolCall `json:"tool_calls"`
+		} `json:"message"`
+		Delta struct {
+			Content   string     `json:"content"`
+			ToolCalls []toolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type chatUsage struct {
+	PromptTokens     int32 `json:"prompt_tokens"`
+	CompletionTokens int32 `json:"completion_tokens"`
+	TotalTokens      int32 `json:"total_tokens"`
+}
+
+func (u *chatUsage) toGenai() *genai.GenerateContentResponseUsageMetadata {
+	if u == nil {
+		return nil
+	}
+	return &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     u.PromptTokens,
+		CandidatesTokenCount: u.CompletionTokens,
+		TotalTokenCount:      u.TotalTokens,
+	}
+}
+
+func buildRequest(modelName string, req *model.LLMRequest, stream bool) (*chatRequest, error) {
+	out := &chatRequest{
+		Model:  modelName,
+		Stream: stream,
+	}
+	if stream {
+		out.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	cfg := req.Config
+	if cfg != nil {
+		if cfg.SystemInstruction != nil {
+			if text := contentText(cfg.SystemInstruction); text != "" {
+				out.Messages = append(out.Messages, chatMessage{Role: "system", Content: text})
+			}
+		}
+		out.Temperature = cfg.Temperature
+		out.TopP = cfg.TopP
+		out.MaxTokens = cfg.MaxOutputTokens
+		out.Stop = cfg.StopSequences
+		out.PresencePenalty = cfg.PresencePenalty
+		out.FrequencyPenalty = cfg.FrequencyPenalty
+		out.Seed = cfg.Seed
+
+		for _, t := range cfg.Tools {
+			if t == nil {
+				continue
+			}
+			for _, decl := range t.FunctionDeclarations {
+				out.Tools = append(out.Tools, chatTool{
+					Type: "function",
+					Function: chatFunction{
+						Name:        decl.Name,
+						Description: decl.Description,
+						Parameters:  declarationParameters(decl),
+					},
+				})
+			}
+		}
+	}
+
+	for _, content := range req.Contents {
+		messages, err := convertContent(content)
+		if err != nil {
+			return nil, err
+		}
+		out.Messages = append(out.Messages, messages...)
+	}
+
+	return out, nil
+}
+
+// convertContent turns a genai turn into chat messages. Parts the chat API
+// cannot carry are an error rather than being dropped.
+func convertContent(content *genai.Content) ([]chatMessage, error) {
+	if content == nil {
+		return nil, nil
+	}
+
+	role := "user"
+	if content.Role == genai.RoleModel {
+		role = "assistant"
+	}
+
+	var messages []chatMessage
+	var parts []contentPart
+	var calls []toolCall
+	hasMedia := false
+
+	for _, part := range content.Parts {
+		switch {
+		case part == nil || part.Thought:
+			continue
+		case part.FunctionResponse != nil:
+			result, _ := json.Marshal(part.FunctionResponse.Response)
+			messages = append(messages, chatMessage{
+				Role:       "tool",
+				ToolCallID: part.FunctionResponse.ID,
+				Name:       part.FunctionResponse.Name,
+				Content:    string(result),
+			})
+		case part.FunctionCall != nil:
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			call := toolCall{ID: part.FunctionCall.ID, Type: "function"}
+			call.Function.Name = part.FunctionCall.Name
+			call.Function.Arguments = string(args)
+			calls = append(calls, call)
+		case part.Text != "":
+			parts = append(parts, contentPart{Type: "text", Text: part.Text})
+		case part.InlineData != nil && strings.HasPrefix(part.InlineData.MIMEType, "image/"):
+			hasMedia = true
+			parts = append(parts, contentPart{
+				Type:     "image_url",
+				ImageURL: &imageURL{URL: dataURL(part.InlineData)},
+			})
+		case part.InlineData != nil && strings.HasPrefix(part.InlineData.MIMEType, "text/"):
+			parts = append(parts, contentPart{Type: "text", Text: string(part.InlineData.Data)})
+		case part.InlineData != nil:
+			// Documents such as PDFs are sent as file parts.
+			hasMedia = true
+			parts = append(parts, contentPart{
+				Type: "file",
+				File: &fileData{Filename: fileName(part.InlineData), FileData: dataURL(part.InlineData)},
+			})
+		case part.FileData != nil && strings.HasPrefix(part.FileData.MIMEType, "image/"):
+			hasMedia = true
+			parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: part.FileData.FileURI}})
+		case part.FileData != nil:
+			return nil, fmt.Errorf("openai: file references of type %s are not supported; attach the file instead", part.FileData.MIMEType)
+		}
+	}
+
+	if len(parts) == 0 && len(calls) == 0 {
+		return messages, nil
+	}
+
+	msg := chatMessage{Role: role, ToolCalls: calls}
+	if hasMedia && role == "user" {
+		msg.Content = parts
+	} else if len(parts) > 0 {
+		// Separate text parts of a user turn, such as an attached text file
+		// and the question about it; model output is already contiguous.
+		sep := ""
+		if role == "user" {
+			sep = "\n\n"
+		}
+		texts := make([]string, len(parts))
+		for i, p := range parts {
+			texts[i] = p.Text
+		}
+		msg.Content = strings.Join(texts, sep)
+	}
+
+	// Tool results must directly follow the assistant message that requested them,
+	// and a user turn's text comes after the tool results it carries.
+	if role == "assistant" {
+		return append([]chatMessage{msg}, messages...), nil
+	}
+	return append(messages, msg), nil
+}
+
+func dataURL(blob *genai.Blob) string {
+	return "data:" + blob.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(blob.Data)
+}
+
+func fileName(blob *genai.Blob) string {
+	if blob.DisplayName != "" {
+		return blob.DisplayName
+	}
+	name := "attachment"
+	if exts, _ := mime.ExtensionsByType(blob.MIMEType); len(exts) > 0 {
+		name += exts[0]
+	}
+	return name
+}
+
+func contentText(content *genai.Content) string {
+	var texts []string
+	for _, part := range content.Parts {
+		if part != nil && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func responseContent(text string, calls []toolCall) *genai.Content {
+	content := &genai.Content{Role: genai.RoleModel}
+	if text != "" {
+		content.Parts = append(content.Parts, genai.NewPartFromText(text))
+	}
+	for _, call := range calls {
+		args := make(map[string]any)
+		if strings.TrimSpace(call.Function.Arguments) != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				args = map[string]any{"_raw": call.Function.Arguments}
+			}
+		}
+		id := call.ID
+		if id == "" {
+			id = "call_" + uuid.NewString()
+		}
+		content.Parts = append(content.Parts, &genai.Part{
+			FunctionCall: &genai.FunctionCall{
+				ID:   id,
+				Name: call.Function.Name,
+				Args: args,
+			},
+		})
+	}
+	if len(content.Parts) == 0 {
+		return nil
+	}
+	return content
+}
+
+func finishReason(reason string) genai.FinishReason {
+	switch reason {
+	case "":
+		return ""
+	case "stop", "tool_calls", "function_call":
+		return genai.FinishReasonStop
+	case "length":
+		return genai.FinishReasonMaxTokens
+	case "content_filter":
+		return genai.FinishReasonSafety
+	default:
+		return genai.FinishReasonOther
+	}
+}
+
+func declarationParameters(decl *genai.FunctionDeclaration) any {
+	if decl.ParametersJsonSchema != nil {
+		return decl.ParametersJsonSchema
+	}
+	if decl.Parameters != nil {
+		return schemaToJSON(decl.Parameters)
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+// schemaToJSON converts a genai.Schema (OpenAPI-style, upper-case types) into
+// a plain JSON Schema object.
+func schemaToJSON(s *genai.Schema) map[string]any {
+	if s == nil {
+		return nil
+	}
+
+	out := make(map[string]any)
+	if s.Type != "" {
+		typ := strings.ToLower(string(s.Type))
+		if s.Nullable != nil && *s.Nullable {
+			out["type"] = []string{typ, "null"}
+		} else {
+			out["type"] = typ
+		}
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	if s.Items != nil {
+		out["items"] = schemaToJSON(s.Items)
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = schemaToJSON(prop)
+		}
+		out["properties"] = props
+	} else if s.Type == genai.TypeObject {
+		out["properties"] = map[string]any{}
+	}
+	if len(s.AnyOf) > 0 {
+		anyOf := make([]any, 0, len(s.AnyOf))
+		for _, sub := range s.AnyOf {
+			anyOf = append(anyOf, schemaToJSON(sub))
+		}
+		out["anyOf"] = anyOf
+	}
+	if s.Minimum != nil {
+		out["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		out["maximum"] = *s.Maximum
+	}
+	if s.Default != nil {
+		out["default"] = s.Default
+	}
+	return out
+}
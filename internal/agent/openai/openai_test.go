@@ -0,0 +1,262 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestBuildRequest(t *testing.T) {
+	temp := float32(0.5)
+	req := &model.LLMRequest{
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText("be brief", genai.RoleUser),
+			Temperature:       &temp,
+			Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{
+				Name:        "search",
+				Description: "Search the web",
+				Parameters: &genai.Schema{
+					Type:       genai.TypeObject,
+					Properties: map[string]*genai.Schema{"query": {Type: genai.TypeString}},
+					Required:   []string{"query"},
+				},
+			}}}},
+		},
+		Contents: []*genai.Content{
+			genai.NewContentFromText("hello", genai.RoleUser),
+			{Role: genai.RoleModel, Parts: []*genai.Part{
+				genai.NewPartFromText("Let me look."),
+				{FunctionCall: &genai.FunctionCall{ID: "call_1", Name: "search", Args: map[string]any{"query": "go"}}},
+			}},
+			{Role: genai.RoleUser, Parts: []*genai.Part{
+				{FunctionResponse: &genai.FunctionResponse{ID: "call_1", Name: "search", Response: map[string]any{"result": "found"}}},
+			}},
+		},
+	}
+
+	out, err := buildRequest("gpt-test", req, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Model != "gpt-test" || !out.Stream || out.StreamOptions == nil || !out.StreamOptions.IncludeUsage {
+		t.Errorf("unexpected request header fields: %+v", out)
+	}
+	if out.Temperature == nil || *out.Temperature != 0.5 {
+		t.Errorf("temperature = %v, want 0.5", out.Temperature)
+	}
+	if len(out.Tools) != 1 || out.Tools[0].Function.Name != "search" {
+		t.Fatalf("tools = %+v", out.Tools)
+	}
+	params, _ := json.Marshal(out.Tools[0].Function.Parameters)
+	if !strings.Contains(string(params), `"type":"object"`) || !strings.Contains(string(params), `"query":{"type":"string"}`) {
+		t.Errorf("parameters = %s", params)
+	}
+
+	roles := make([]string, len(out.Messages))
+	for i, m := range out.Messages {
+		roles[i] = m.Role
+	}
+	if got, want := strings.Join(roles, ","), "system,user,assistant,tool"; got != want {
+		t.Fatalf("roles = %s, want %s", got, want)
+	}
+	if out.Messages[0].Content != "be brief" || out.Messages[1].Content != "hello" {
+		t.Errorf("system/user content = %v / %v", out.Messages[0].Content, out.Messages[1].Content)
+	}
+	assistant := out.Messages[2]
+	if assistant.Content != "Let me look." || len(assistant.ToolCalls) != 1 {
+		t.Fatalf("assistant = %+v", assistant)
+	}
+	if call := assistant.ToolCalls[0]; call.ID != "call_1" || call.Function.Name != "search" || call.Function.Arguments != `{"query":"go"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if tool := out.Messages[3]; tool.ToolCallID != "call_1" || tool.Content != `{"result":"found"}` {
+		t.Errorf("tool message = %+v", tool)
+	}
+}
+
+func TestConvertContent(t *testing.T) {
+	png := &genai.Blob{MIMEType: "image/png", Data: []byte{1, 2, 3}}
+	pdf := &genai.Blob{MIMEType: "application/pdf", Data: []byte("%PDF")}
+	text := &genai.Blob{MIMEType: "text/plain", Data: []byte("notes")}
+
+	tests := []struct {
+		name    string
+		parts   []*genai.Part
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "text parts are joined",
+			parts: []*genai.Part{genai.NewPartFromText("a"), {InlineData: text}},
+			want:  `[{"role":"user","content":"a\n\nnotes"}]`,
+		},
+		{
+			name:  "image becomes a data URL",
+			parts: []*genai.Part{{InlineData: png}, genai.NewPartFromText("what is this?")},
+			want:  `[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,AQID"}},{"type":"text","text":"what is this?"}]}]`,
+		},
+		{
+			name:  "documents become file parts",
+			parts: []*genai.Part{{InlineData: pdf}},
+			want:  `[{"role":"user","content":[{"type":"file","file":{"filename":"attachment.pdf","file_data":"data:application/pdf;base64,JVBERg=="}}]}]`,
+		},
+		{
+			name:  "thoughts are skipped",
+			parts: []*genai.Part{{Text: "hmm", Thought: true}, genai.NewPartFromText("hi")},
+			want:  `[{"role":"user","content":"hi"}]`,
+		},
+		{
+			name:    "file references other than images are an error",
+			parts:   []*genai.Part{{FileData: &genai.FileData{MIMEType: "application/pdf", FileURI: "gs://bucket/doc.pdf"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := convertContent(&genai.Content{Role: genai.RoleUser, Parts: tt.parts})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", messages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(messages)
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// sseServer serves the given chunks as a chat completions event stream and
+// records the last request body.
+func sseServer(t *testing.T, chunks ...string) (*httptest.Server, *[]byte) {
+	t.Helper()
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &body
+}
+
+func collect(t *testing.T, llm model.LLM, req *model.LLMRequest) []*model.LLMResponse {
+	t.Helper()
+	var responses []*model.LLMResponse
+	for resp, err := range llm.GenerateContent(context.Background(), req, true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestStreamText(t *testing.T) {
+	srv, body := sseServer(t,
+		`{"choices":[{"delta":{"content":"Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`,
+	)
+	llm, err := NewModel("m", Config{BaseURL: srv.URL + "/", APIKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := collect(t, llm, &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("hi", genai.RoleUser)}})
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 2 partials and a final one", len(responses))
+	}
+	for i, want := range []string{"Hel", "lo"} {
+		if !responses[i].Partial || responses[i].Content.Parts[0].Text != want {
+			t.Errorf("partial %d = %+v", i, responses[i])
+		}
+	}
+	final := responses[2]
+	if final.Partial || !final.TurnComplete || final.Content.Parts[0].Text != "Hello" {
+		t.Errorf("final = %+v", final)
+	}
+	if final.FinishReason != genai.FinishReasonStop {
+		t.Errorf("finish reason = %s", final.FinishReason)
+	}
+	if u := final.UsageMetadata; u == nil || u.PromptTokenCount != 7 || u.CandidatesTokenCount != 2 || u.TotalTokenCount != 9 {
+		t.Errorf("usage = %+v", final.UsageMetadata)
+	}
+	if !strings.Contains(string(*body), `"stream":true`) || !strings.Contains(string(*body), `"include_usage":true`) {
+		t.Errorf("request = %s", *body)
+	}
+}
+
+func TestStreamToolCalls(t *testing.T) {
+	// Arguments arrive in pieces and two calls are interleaved by index.
+	srv, _ := sseServer(t,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"read_file","arguments":"{\"pa"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"list_dir","arguments":"{}"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"go.mod\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+	)
+	llm, _ := NewModel("m", Config{BaseURL: srv.URL})
+
+	responses := collect(t, llm, &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("hi", genai.RoleUser)}})
+	if len(responses) != 1 {
+		t.Fatalf("got %d responses, want only the final one", len(responses))
+	}
+	parts := responses[0].Content.Parts
+	if len(parts) != 2 {
+		t.Fatalf("parts = %+v", parts)
+	}
+	first, second := parts[0].FunctionCall, parts[1].FunctionCall
+	if first == nil || first.ID != "call_a" || first.Name != "read_file" || first.Args["path"] != "go.mod" {
+		t.Errorf("first call = %+v", first)
+	}
+	if second == nil || second.ID != "call_b" || second.Name != "list_dir" || len(second.Args) != 0 {
+		t.Errorf("second call = %+v", second)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	srv, _ := sseServer(t, `{"error":{"message":"rate limited"}}`)
+	llm, _ := NewModel("m", Config{BaseURL: srv.URL})
+	var gotErr error
+	for _, err := range llm.GenerateContent(context.Background(), &model.LLMRequest{}, true) {
+		if err != nil {
+			gotErr = err
+		}
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "rate limited") {
+		t.Errorf("error = %v", gotErr)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"bad key"}}`)
+	}))
+	defer failing.Close()
+	llm, _ = NewModel("m", Config{BaseURL: failing.URL})
+	for _, err := range llm.GenerateContent(context.Background(), &model.LLMRequest{}, true) {
+		gotErr = err
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "bad key (status 401)") {
+		t.Errorf("error = %v", gotErr)
+	}
+}
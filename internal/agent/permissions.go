@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"axe-desktop/pkg/models"
+
+	"google.golang.org/adk/tool"
+)
+
+// ApprovalRequest asks the user whether a tool call may run.
+type ApprovalRequest struct {
+	SessionID string
+	ToolName  string
+	Args      map[string]any
+	// Preview is the diff a file write would make, or "" for other tools.
+	Preview string
+	// AlwaysAsk is set for tools whose answer cannot be remembered.
+	AlwaysAsk bool
+}
+
+// Approval is the user's answer to an ApprovalRequest. With Remember set the
+// answer is reused for the tool for the rest of the session.
+type Approval struct {
+	Allow    bool
+	Remember bool
+}
+
+// ApprovalHandler asks the user to approve a tool call. It blocks until the
+// user answers or ctx is done, in which case it returns ctx's error.
+type ApprovalHandler func(ctx context.Context, req ApprovalRequest) (Approval, error)
+
+// SetApprovalHandler sets how tool calls with the ask policy are approved.
+// Without a handler they run as if allowed, except tools that always ask.
+func (s *Service) SetApprovalHandler(handler ApprovalHandler) {
+	s.mu.Lock()
+	s.approve = handler
+	s.mu.Unlock()
+}
+
+// beforeTool applies the tool's permission policy before it runs. Returning
+// a result skips the tool; the model sees it like a tool error. Tools that
+// always ask do not run at all without an approval handler.
+func (s *Service) beforeTool(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+	sessionID := ctx.SessionID()
+	alwaysAsk := alwaysAskTools[t.Name()]
+	switch s.toolPolicy(sessionID, t.Name()) {
+	case models.ToolPolicyAllow:
+		if !alwaysAsk {
+			return nil, nil
+		}
+	case models.ToolPolicyDeny:
+		return denied(t.Name()), nil
+	}
+
+	s.mu.RLock()
+	approve := s.approve
+	s.mu.RUnlock()
+	if approve == nil {
+		if alwaysAsk {
+			return denied(t.Name()), nil
+		}
+		return nil, nil
+	}
+
+	// A write that cannot be previewed would fail anyway, so the error is
+	// returned to the model without asking the user.
+	preview, err := s.writePreview(sessionID, t.Name(), args)
+	if err != nil {
+		return map[string]any{"error": err.Error()}, nil
+	}
+
+	approval, err := approve(ctx, ApprovalRequest{
+		SessionID: sessionID,
+		ToolName:  t.Name(),
+		Args:      args,
+		Preview:   preview,
+		AlwaysAsk: alwaysAsk,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if approval.Remember && !alwaysAsk {
+		policy := models.ToolPolicyDeny
+		if approval.Allow {
+			policy = models.ToolPolicyAllow
+		}
+		s.mu.Lock()
+		if s.approvals[sessionID] == nil {
+			s.approvals[sessionID] = make(map[string]models.ToolPolicy)
+		}
+		s.approvals[sessionID][t.Name()] = policy
+		s.mu.Unlock()
+	}
+	if !approval.Allow {
+		return denied(t.Name()), nil
+	}
+	return nil, nil
+}
+
+// toolPolicy resolves the policy for a tool: a deny policy always applies,
+// then answers remembered for the session, then the tool's own policy.
+func (s *Service) toolPolicy(sessionID, toolName string) models.ToolPolicy {
+	policies, err := s.storage.GetToolPolicies()
+	if err != nil {
+		fmt.Printf("[Agent] failed to load tool policies: %v\n", err)
+	}
+	policy, ok := policies[toolName]
+	if !ok {
+		policy = DefaultPolicy(toolName)
+	}
+	if policy == models.ToolPolicyDeny {
+		return policy
+	}
+
+	s.mu.RLock()
+	remembered, ok := s.approvals[sessionID][toolName]
+	s.mu.RUnlock()
+	if ok {
+		return remembered
+	}
+	return policy
+}
+
+// DefaultPolicy is the policy of a tool that has none stored. The read-only
+// file tools are allowed; everything else asks.
+func DefaultPolicy(toolName string) models.ToolPolicy {
+	if readOnlyTools[toolName] {
+		return models.ToolPolicyAllow
+	}
+	return models.DefaultToolPolicy
+}
+
+func denied(toolName string) map[string]any {
+	return map[string]any{
+		"error": fmt.Sprintf("Permission denied: the user did not allow %s to run. Do not retry it; continue without it.", toolName),
+	}
+}
@@ -6,18 +6,20 @@ import (
 	"strings"
 	"sync"
 
+	"axe-desktop/internal/agent/openai"
+	"axe-desktop/internal/attachment"
 	"axe-desktop/internal/config"
 	"axe-desktop/internal/storage"
+	"axe-desktop/internal/workspace"
 	"axe-desktop/pkg/models"
 
-	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
 	"google.golang.org/adk/model/gemini"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
-	"google.golang.org/adk/tool/mcptoolset"
 	"google.golang.org/genai"
 )
 
@@ -25,79 +27,136 @@ type Service struct {
 	config         *config.Config
 	storage        *storage.Storage
 	sessionService session.Service
-	runners        map[string]*runner.Runner
-	cancelFuncs    map[string]context.CancelFunc
-	mu             sync.RWMutex
+	runners        map[string]*sessionRunner
+	generations    map[string]*generation
+	stdioServers   map[string]*stdioServer
+	approve        ApprovalHandler
+	onToolOutput   ToolOutputHandler
+	// contexts holds the description of each workspace sent to the model.
+	contexts workspace.ContextCache
+	// approvals holds the answers the user asked to remember, by session
+	// and tool.
+	approvals map[string]map[string]models.ToolPolicy
+	// running counts the replies being generated, so Close can wait for
+	// them to save their state. No reply starts once closed is set.
+	running sync.WaitGroup
+	closed  bool
+	mu      sync.RWMutex
+}
+
+const (
+	appName   = "axe-desktop"
+	userID    = "default"
+	agentName = "axe-agent"
+)
+
+// runnerConfig captures everything a session's runner is built from, so a
+// runner is rebuilt whenever the session's prompt, provider or model changes.
+type runnerConfig struct {
+	provider    models.Provider
+	instruction string
+	// workspace is the folder the file tools work in, or nil for none.
+	workspace *workspace.Workspace
+}
+
+// equal reports whether a runner built from c can serve o. Workspaces are
+// compared by folder, as each resolve opens its own.
+func (c runnerConfig) equal(o runnerConfig) bool {
+	if (c.workspace == nil) != (o.workspace == nil) {
+		return false
+	}
+	if c.workspace != nil && c.workspace.Root() != o.workspace.Root() {
+		return false
+	}
+	c.workspace, o.workspace = nil, nil
+	return c == o
+}
+
+type sessionRunner struct {
+	runner *runner.Runner
+	config runnerConfig
+}
+
+// generation is a reply being generated. Each has its own identity so a
+// finished generation never removes the entry of a newer one.
+type generation struct {
+	cancel    context.CancelFunc
+	toolCalls *toolCallRecorder
 }
 
 type MessageHandler func(role, content string)
-type ToolCallHandler func(toolName string, args, result map[string]any, err error)
+type ToolCallHandler func(call models.ToolCall)
 type DebugHandler func(line string)
+type DoneHandler func(status models.MessageStatus)
 
 func NewService(cfg *config.Config, store *storage.Storage) (*Service, error) {
 	return &Service{
 		config:         cfg,
 		storage:        store,
-		sessionService: session.InMemoryService(),
-		runners:        make(map[string]*runner.Runner),
-		cancelFuncs:    make(map[string]context.CancelFunc),
+		sessionService: store.SessionService(),
+		runners:        make(map[string]*sessionRunner),
+		generations:    make(map[string]*generation),
+		stdioServers:   make(map[string]*stdioServer),
+		approvals:      make(map[string]map[string]models.ToolPolicy),
 	}, nil
 }
 
-func (s *Service) getOrCreateRunner(sessionID string, provider *models.Provider) (*runner.Runner, error) {
+func (s *Service) getOrCreateRunner(sessionID string, cfg runnerConfig) (*runner.Runner, error) {
 	s.mu.RLock()
-	r, exists := s.runners[sessionID]
+	existing, exists := s.runners[sessionID]
 	s.mu.RUnlock()
 
-	if exists {
-		return r, nil
+	if exists && existing.config.equal(cfg) {
+		return existing.runner, nil
 	}
 
 	ctx := context.Background()
 
-	model, err := gemini.NewModel(ctx, provider.Model, &genai.ClientConfig{
-		APIKey: provider.APIKey,
-	})
+	llm, err := newModel(ctx, &cfg.provider)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create model: %w", err)
 	}
 
-	var agentToolsets []tool.Toolset
-	for _, mcpSrv := range s.config.MCPServers {
-		if !mcpSrv.Enabled {
-			continue
-		}
+	agentToolsets := s.mcpToolsets()
 
-		var transport mcp.Transport
-		if mcpSrv.Type == models.MCPServerHTTP {
-			transport = &mcp.StreamableClientTransport{
-				Endpoint: mcpSrv.URL,
-			}
-		} else {
-			continue
+	var agentTools []tool.Tool
+	if cfg.workspace != nil {
+		agentTools, err = s.fileTools()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file tools: %w", err)
 		}
-
-		exaToolset, err := mcptoolset.New(mcptoolset.Config{
-			Transport: transport,
-		})
-		if err == nil {
-			agentToolsets = append(agentToolsets, exaToolset)
+		runCommand, err := s.commandTool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create command tool: %w", err)
 		}
+		agentTools = append(agentTools, runCommand)
 	}
 
+	// The instruction is passed through a provider so ADK does not treat
+	// braces in prompts or summaries as session state placeholders. The
+	// workspace context is added per request from the cache, so changes to
+	// the files show up without rebuilding the runner.
+	instruction, ws := cfg.instruction, cfg.workspace
 	llmAgent, err := llmagent.New(llmagent.Config{
-		Name:        "axe-agent",
-		Model:       model,
+		Name:        agentName,
+		Model:       llm,
 		Description: "Axe Desktop Assistant",
-		Instruction: "You are a helpful AI assistant. Search the web when needed.",
-		Toolsets:    agentToolsets,
+		InstructionProvider: func(agent.ReadonlyContext) (string, error) {
+			if ws == nil {
+				return instruction, nil
+			}
+			return instruction + "\n\n" + fmt.Sprintf(workspaceInstruction, ws.Root()) + "\n\n" + s.contexts.Context(ws), nil
+		},
+		Tools:               agentTools,
+		Toolsets:            agentToolsets,
+		BeforeToolCallbacks: []llmagent.BeforeToolCallback{s.beforeTool},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
 	}
 
-	r, err = runner.New(runner.Config{
-		AppName:        "axe-desktop",
+	r, err := runner.New(runner.Config{
+		AppName:        appName,
 		Agent:          llmAgent,
 		SessionService: s.sessionService,
 	})
@@ -106,16 +165,73 @@ func (s *Service) getOrCreateRunner(sessionID string, provider *models.Provider)
 	}
 
 	s.mu.Lock()
-	s.runners[sessionID] = r
+	s.runners[sessionID] = &sessionRunner{runner: r, config: cfg}
 	s.mu.Unlock()
 
 	return r, nil
 }
 
+// resolveRunnerConfig builds the runner configuration from the session's own
+// provider, model and system prompt, falling back to the active provider.
+func (s *Service) resolveRunnerConfig(sessionID string) (runnerConfig, error) {
+	sess, err := s.storage.GetSession(sessionID)
+	if err != nil {
+		return runnerConfig{}, err
+	}
+
+	provider := s.config.GetProvider(sess.ProviderID)
+	if provider == nil {
+		provider = s.config.GetActiveProvider()
+	}
+	if provider == nil {
+		return runnerConfig{}, fmt.Errorf("no active provider configured")
+	}
+
+	cfg := runnerConfig{
+		provider:    *provider,
+		instruction: sess.SystemPrompt,
+	}
+	if sess.Model != "" {
+		cfg.provider.Model = sess.Model
+	}
+	if cfg.instruction == "" {
+		cfg.instruction = models.DefaultSystemPrompt
+	}
+	if sess.SummaryUntil != nil && sess.ContextSummary != nil {
+		cfg.instruction += "\n\n" + summaryPreamble + *sess.ContextSummary
+	}
+
+	if sess.Workspace != "" {
+		ws, err := workspace.New(sess.Workspace)
+		if err != nil {
+			fmt.Printf("[Agent] workspace unavailable, file tools disabled: %v\n", err)
+		} else {
+			cfg.workspace = ws
+		}
+	}
+	return cfg, nil
+}
+
+func newModel(ctx context.Context, provider *models.Provider) (model.LLM, error) {
+	switch provider.Type {
+	case models.ProviderOpenAI:
+		return openai.NewModel(provider.Model, openai.Config{
+			APIKey:  provider.APIKey,
+			BaseURL: provider.BaseURL,
+		})
+	case models.ProviderGemini, "":
+		return gemini.NewModel(ctx, provider.Model, &genai.ClientConfig{
+			APIKey: provider.APIKey,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported provider type: %s", provider.Type)
+	}
+}
+
 func (s *Service) ensureSession(ctx context.Context, sessionID string) (string, error) {
 	_, err := s.sessionService.Get(ctx, &session.GetRequest{
-		AppName:   "axe-desktop",
-		UserID:    "default",
+		AppName:   appName,
+		UserID:    userID,
 		SessionID: sessionID,
 	})
 	if err == nil {
@@ -123,25 +239,126 @@ func (s *Service) ensureSession(ctx context.Context, sessionID string) (string,
 	}
 
 	createResp, err := s.sessionService.Create(ctx, &session.CreateRequest{
-		AppName:   "axe-desktop",
-		UserID:    "default",
+		AppName:   appName,
+		UserID:    userID,
 		SessionID: sessionID,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 
+	if err := s.seedHistory(ctx, createResp.Session); err != nil {
+		return "", fmt.Errorf("failed to restore history: %w", err)
+	}
+
 	return createResp.Session.ID(), nil
 }
 
-func (s *Service) SendMessage(ctx context.Context, sessionID string, content string,
-	onMessage MessageHandler, onToolCall ToolCallHandler, onDebug DebugHandler) error {
+// seedHistory replays messages stored before the session had ADK events (e.g.
+// chats created by older versions, or after the history was summarized) so
+// the model sees the prior conversation. Messages covered by the context
+// summary are skipped; the summary is part of the instruction instead.
+func (s *Service) seedHistory(ctx context.Context, sess session.Session) error {
+	stored, err := s.storage.GetSession(sess.ID())
+	if err != nil {
+		return err
+	}
+	messages, err := s.storage.ListMessages(sess.ID(), 0, 0)
+	if err != nil {
+		return err
+	}
 
-	provider := s.config.GetActiveProvider()
-	if provider == nil {
-		return fmt.Errorf("no active provider configured")
+	attachments, err := s.storage.ListAttachments(sess.ID())
+	if err != nil {
+		return err
+	}
+	byMessage := make(map[string][]models.Attachment)
+	for _, a := range attachments {
+		byMessage[a.MessageID] = append(byMessage[a.MessageID], a)
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Content == "" && len(byMessage[msg.ID]) == 0 {
+			continue
+		}
+		if stored.SummaryUntil != nil && !msg.CreatedAt.After(*stored.SummaryUntil) {
+			continue
+		}
+
+		event := session.NewEvent("restored-" + msg.ID)
+		event.Timestamp = msg.CreatedAt
+		switch msg.Role {
+		case models.RoleUser:
+			event.Author = "user"
+			// Attachments whose files were removed are left out of the
+			// history rather than failing the whole session.
+			parts, _ := userParts(msg.Content, byMessage[msg.ID], true)
+			if len(parts) == 0 {
+				continue
+			}
+			event.Content = genai.NewContentFromParts(parts, genai.RoleUser)
+		case models.RoleAssistant:
+			if msg.Content == "" {
+				continue
+			}
+			event.Author = agentName
+			event.Content = genai.NewContentFromText(msg.Content, genai.RoleModel)
+		default:
+			continue
+		}
+
+		if err := s.sessionService.AppendEvent(ctx, sess, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendMessage sends a user message with optional attachments and streams the
+// reply. The attachments must already be saved under the attachment
+// directory; they are linked to the new message here.
+func (s *Service) SendMessage(ctx context.Context, sessionID string, content string, attachments []models.Attachment,
+	onMessage MessageHandler, onToolCall ToolCallHandler, onDebug DebugHandler, onDone DoneHandler) error {
+
+	// The slot is reserved under the same lock as the check, so two sends to
+	// one session cannot both start. It is released if the setup fails.
+	streamCtx, cancel := context.WithCancel(ctx)
+	gen := &generation{cancel: cancel}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		cancel()
+		return fmt.Errorf("the agent is shutting down")
+	}
+	if _, busy := s.generations[sessionID]; busy {
+		s.mu.Unlock()
+		cancel()
+		return fmt.Errorf("a reply is already being generated in this session")
+	}
+	s.generations[sessionID] = gen
+	s.running.Add(1)
+	s.mu.Unlock()
+	started := false
+	defer func() {
+		if !started {
+			s.releaseGeneration(sessionID, gen)
+			cancel()
+			s.running.Done()
+		}
+	}()
+
+	parts, err := userParts(content, attachments, false)
+	if err != nil {
+		return err
 	}
-	if provider.APIKey == "" {
+
+	cfg, err := s.resolveRunnerConfig(sessionID)
+	if err != nil {
+		return err
+	}
+	provider := &cfg.provider
+	if provider.APIKey == "" && provider.Type != models.ProviderOpenAI {
 		return fmt.Errorf("no API key configured for provider %s", provider.Name)
 	}
 	if onDebug != nil {
@@ -150,7 +367,7 @@ func (s *Service) SendMessage(ctx context.Context, sessionID string, content str
 	fmt.Printf("[Agent] provider=%s model=%s\n", provider.Name, provider.Model)
 	fmt.Printf("[Agent] api_key_len=%d\n", len(provider.APIKey))
 
-	r, err := s.getOrCreateRunner(sessionID, provider)
+	r, err := s.getOrCreateRunner(sessionID, cfg)
 	if err != nil {
 		return err
 	}
@@ -164,65 +381,163 @@ func (s *Service) SendMessage(ctx context.Context, sessionID string, content str
 		SessionID: sessionID,
 		Role:      models.RoleUser,
 		Content:   content,
+		Status:    models.StatusCompleted,
 	}
 	if err := s.storage.CreateMessage(userMsg); err != nil {
 		return err
 	}
+	for i := range attachments {
+		attachments[i].SessionID = sessionID
+		attachments[i].MessageID = userMsg.ID
+		if err := s.storage.CreateAttachment(&attachments[i]); err != nil {
+			return err
+		}
+	}
 
 	assistantMsg := &models.Message{
 		SessionID: sessionID,
 		Role:      models.RoleAssistant,
 		Content:   "",
+		Status:    models.StatusInProgress,
+		// The model is recorded so usage is attributed correctly after the
+		// session switches models.
+		Metadata: map[string]any{
+			"provider_id": provider.ID,
+			"model":       provider.Model,
+		},
 	}
 	if err := s.storage.CreateMessage(assistantMsg); err != nil {
 		return err
 	}
 
-	streamCtx, cancel := context.WithCancel(ctx)
-	s.mu.Lock()
-	s.cancelFuncs[sessionID] = cancel
-	s.mu.Unlock()
+	gen.toolCalls = newToolCallRecorder(s.storage, sessionID, assistantMsg.ID)
+	userContent := genai.NewContentFromParts(parts, genai.RoleUser)
 
-	userContent := genai.NewContentFromText(content, genai.RoleUser)
-
-	go s.handleStreaming(streamCtx, r, adkSessionID, assistantMsg, userContent, onMessage, onToolCall, onDebug)
+	started = true
+	go s.handleStreaming(streamCtx, gen, r, adkSessionID, assistantMsg, userContent, onMessage, onToolCall, onDebug, onDone)
 
 	return nil
 }
 
-func (s *Service) handleStreaming(ctx context.Context, r *runner.Runner, sessionID string,
-	assistantMsg *models.Message, userContent *genai.Content,
-	onMessage MessageHandler, onToolCall ToolCallHandler, onDebug DebugHandler) {
+// userParts builds the model input for a user message: the attachments
+// followed by the text. With skipMissing, attachments that cannot be read are
+// dropped instead of returning an error.
+func userParts(content string, attachments []models.Attachment, skipMissing bool) ([]*genai.Part, error) {
+	var parts []*genai.Part
+	for _, a := range attachments {
+		part, err := attachment.Part(a)
+		if err != nil {
+			if skipMissing {
+				continue
+			}
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	if content != "" {
+		parts = append(parts, genai.NewPartFromText(content))
+	}
+	return parts, nil
+}
 
-	var fullResponse strings.Builder
+func (s *Service) handleStreaming(ctx context.Context, gen *generation, r *runner.Runner, sessionID string,
+	assistantMsg *models.Message, userContent *genai.Content,
+	onMessage MessageHandler, onToolCall ToolCallHandler, onDebug DebugHandler, onDone DoneHandler) {
+	defer s.running.Done()
+
+	// committed holds text from finished model turns; partial holds the text
+	// streamed so far for the current turn, which the final aggregated event
+	// replaces.
+	var committed strings.Builder
+	var partial strings.Builder
 	var gotContent bool
+	// failure is the error shown to the user, if the generation failed.
+	var failure string
+	var usage tokenUsage
 	if onDebug != nil {
 		onDebug(fmt.Sprintf("session=%s start", sessionID))
 	}
 	fmt.Printf("[Agent] session=%s start\n", sessionID)
 
+	toolCalls := gen.toolCalls
+
+	response := func() string {
+		if committed.Len() > 0 && partial.Len() > 0 {
+			return committed.String() + "\n\n" + partial.String()
+		}
+		return committed.String() + partial.String()
+	}
+
+	// Release the context once the reply is saved; it is checked below to
+	// tell a cancelled generation from a finished one.
+	defer gen.cancel()
 	defer func() {
-		s.mu.Lock()
-		delete(s.cancelFuncs, assistantMsg.SessionID)
-		s.mu.Unlock()
+		s.releaseGeneration(assistantMsg.SessionID, gen)
 
-		assistantMsg.Content = fullResponse.String()
-		s.storage.UpdateMessage(assistantMsg)
+		reason := "no response received from tool"
+		if ctx.Err() != nil {
+			reason = "cancelled before the tool returned"
+		}
+		for _, tc := range toolCalls.abandon(reason) {
+			onToolCall(*tc)
+		}
+
+		assistantMsg.Content = response()
+		usage.record(assistantMsg)
+		switch {
+		case ctx.Err() != nil:
+			assistantMsg.Status = models.StatusCancelled
+		case failure != "":
+			assistantMsg.Status = models.StatusFailed
+			if assistantMsg.Metadata == nil {
+				assistantMsg.Metadata = map[string]any{}
+			}
+			assistantMsg.Metadata["error"] = failure
+		default:
+			assistantMsg.Status = models.StatusCompleted
+		}
+		if err := s.storage.UpdateMessage(assistantMsg); err != nil {
+			fmt.Printf("[Agent] failed to update message %s: %v\n", assistantMsg.ID, err)
+		}
+
+		if assistantMsg.Status == models.StatusCompleted {
+			s.compactIfNeeded(assistantMsg.SessionID, usage.context)
+		}
+
+		if onDone != nil {
+			onDone(assistantMsg.Status)
+		}
 	}()
 
+	cancelled := func() bool {
+		if ctx.Err() == nil {
+			return false
+		}
+		onMessage("assistant", response())
+		if onDebug != nil {
+			onDebug("cancelled")
+		}
+		fmt.Println("[Agent] cancelled")
+		return true
+	}
+
 	stream := func(mode agent.StreamingMode) bool {
 		if onDebug != nil {
 			onDebug(fmt.Sprintf("streaming_mode=%v", mode))
 		}
 		fmt.Printf("[Agent] streaming_mode=%v\n", mode)
-		events := r.Run(ctx, "default", sessionID, userContent, agent.RunConfig{
+		events := r.Run(ctx, userID, sessionID, userContent, agent.RunConfig{
 			StreamingMode: mode,
 		})
 		eventCount := 0
 
 		for event, err := range events {
 			eventCount++
+			if cancelled() {
+				return true
+			}
 			if err != nil {
+				failure = err.Error()
 				onMessage("system", fmt.Sprintf("Error: %v", err))
 				if onDebug != nil {
 					onDebug(fmt.Sprintf("error=%v", err))
@@ -238,54 +553,68 @@ func (s *Service) handleStreaming(ctx context.Context, r *runner.Runner, session
 				continue
 			}
 
-			select {
-			case <-ctx.Done():
-				onMessage("assistant", fullResponse.String()+"\n[Cancelled]")
-				return gotContent
-			default:
-				if event.ErrorCode != "" {
-					onMessage("system", fmt.Sprintf("Error: %s - %s", event.ErrorCode, event.ErrorMessage))
-					if onDebug != nil {
-						onDebug(fmt.Sprintf("error=%s message=%s", event.ErrorCode, event.ErrorMessage))
-					}
-					fmt.Printf("[Agent] error=%s message=%s\n", event.ErrorCode, event.ErrorMessage)
-					return true
+			if event.ErrorCode != "" {
+				failure = fmt.Sprintf("%s - %s", event.ErrorCode, event.ErrorMessage)
+				onMessage("system", fmt.Sprintf("Error: %s - %s", event.ErrorCode, event.ErrorMessage))
+				if onDebug != nil {
+					onDebug(fmt.Sprintf("error=%s message=%s", event.ErrorCode, event.ErrorMessage))
 				}
+				fmt.Printf("[Agent] error=%s message=%s\n", event.ErrorCode, event.ErrorMessage)
+				return true
+			}
 
-				if event.Content != nil {
-					if onDebug != nil && !gotContent {
-						onDebug("content=present")
-					}
-					if !gotContent {
-						fmt.Println("[Agent] content=present")
-					}
-					for _, part := range event.Content.Parts {
-						if part.Text != "" {
-							fullResponse.WriteString(part.Text)
-							onMessage("assistant", fullResponse.String())
-							gotContent = true
-						}
+			if !event.Partial {
+				usage.add(event.UsageMetadata)
+			}
 
-						if part.FunctionCall != nil {
-							onToolCall(part.FunctionCall.Name, part.FunctionCall.Args, nil, nil)
-							if onDebug != nil {
-								onDebug(fmt.Sprintf("tool_call=%s", part.FunctionCall.Name))
-							}
-							fmt.Printf("[Agent] tool_call=%s\n", part.FunctionCall.Name)
+			if event.Content == nil {
+				continue
+			}
+			if onDebug != nil && !gotContent {
+				onDebug("content=present")
+			}
+			if !gotContent {
+				fmt.Println("[Agent] content=present")
+			}
+			for _, part := range event.Content.Parts {
+				if part.Text != "" && !part.Thought {
+					if event.Partial {
+						partial.WriteString(part.Text)
+					} else {
+						partial.Reset()
+						if committed.Len() > 0 {
+							committed.WriteString("\n\n")
 						}
+						committed.WriteString(part.Text)
+					}
+					onMessage("assistant", response())
+					gotContent = true
+				}
 
-						if part.FunctionResponse != nil {
-							onToolCall(part.FunctionResponse.Name, nil, part.FunctionResponse.Response, nil)
-							if onDebug != nil {
-								onDebug(fmt.Sprintf("tool_response=%s", part.FunctionResponse.Name))
-							}
-							fmt.Printf("[Agent] tool_response=%s\n", part.FunctionResponse.Name)
-						}
+				if part.FunctionCall != nil {
+					tc := toolCalls.start(part.FunctionCall)
+					onToolCall(*tc)
+					if onDebug != nil {
+						onDebug(fmt.Sprintf("tool_call=%s", part.FunctionCall.Name))
+					}
+					fmt.Printf("[Agent] tool_call=%s\n", part.FunctionCall.Name)
+				}
+
+				if part.FunctionResponse != nil {
+					tc := toolCalls.finish(part.FunctionResponse)
+					onToolCall(*tc)
+					if onDebug != nil {
+						onDebug(fmt.Sprintf("tool_response=%s", part.FunctionResponse.Name))
 					}
+					fmt.Printf("[Agent] tool_response=%s\n", part.FunctionResponse.Name)
 				}
 			}
 		}
 
+		if cancelled() {
+			return true
+		}
+
 		if eventCount == 0 {
 			msg := "runner returned 0 events"
 			if onDebug != nil {
@@ -305,16 +634,80 @@ func (s *Service) handleStreaming(ctx context.Context, r *runner.Runner, session
 		return
 	}
 
-	onMessage("system", "No response received. Check the model name and API key.")
+	failure = "No response received. Check the model name and API key."
+	onMessage("system", failure)
 	if onDebug != nil {
 		onDebug("no_response")
 	}
 	fmt.Println("[Agent] no_response")
 }
 
+// Cancel stops the in-flight generation for a session. The partial response is
+// kept and the assistant message is marked cancelled. It reports whether a
+// generation was running.
+func (s *Service) Cancel(sessionID string) bool {
+	s.mu.RLock()
+	gen, ok := s.generations[sessionID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+	gen.cancel()
+	return true
+}
+
+// releaseGeneration removes a generation's entry unless a newer one has
+// replaced it.
+func (s *Service) releaseGeneration(sessionID string, gen *generation) {
+	s.mu.Lock()
+	if s.generations[sessionID] == gen {
+		delete(s.generations, sessionID)
+	}
+	s.mu.Unlock()
+}
+
+// Generating reports whether a reply is being generated for a session.
+func (s *Service) Generating(sessionID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.generations[sessionID]
+	return ok
+}
+
+// RemoveRunner drops a session's runner so the next message rebuilds it
+// from the current settings.
 func (s *Service) RemoveRunner(sessionID string) {
 	s.mu.Lock()
 	delete(s.runners, sessionID)
-	delete(s.cancelFuncs, sessionID)
 	s.mu.Unlock()
 }
+
+// CloseSession releases everything held for a session that is deleted or
+// archived: its generation is stopped, and its runner and remembered tool
+// approvals are dropped.
+func (s *Service) CloseSession(sessionID string) {
+	s.mu.Lock()
+	delete(s.runners, sessionID)
+	delete(s.approvals, sessionID)
+	if gen, ok := s.generations[sessionID]; ok {
+		gen.cancel()
+		delete(s.generations, sessionID)
+	}
+	s.mu.Unlock()
+}
+
+// Close cancels in-flight generations, waits for them to save what they
+// have, and stops stdio MCP server processes. The storage must stay open
+// until it returns.
+func (s *Service) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for _, gen := range s.generations {
+		gen.cancel()
+	}
+	s.mu.Unlock()
+
+	s.running.Wait()
+	s.stopStdioServers()
+}
@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"axe-desktop/internal/config"
+	"axe-desktop/internal/storage"
+	"axe-desktop/pkg/models"
+)
+
+// newTestService returns a service whose only provider is an OpenAI-compatible
+// endpoint served by handler, and a session that uses it.
+func newTestService(t *testing.T, handler http.HandlerFunc) (*Service, *storage.Storage, *models.Session) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	cfg := &config.Config{
+		LogDir: t.TempDir(),
+		Providers: []models.Provider{{
+			ID: "test", Name: "Test", Type: models.ProviderOpenAI, BaseURL: srv.URL, Model: "test-model", Enabled: true,
+		}},
+		ActiveProviderID: "test",
+	}
+	s, err := NewService(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+
+	session := &models.Session{UserID: "default", Title: "Test", ProviderID: "test", Model: "test-model"}
+	if err := store.CreateSession(session); err != nil {
+		t.Fatal(err)
+	}
+	return s, store, session
+}
+
+// replyText answers every request with a short streamed reply.
+func replyText(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"},\"finish_reason\":\"stop\"}]}\n\n")
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func TestSendMessageOneGenerationPerSession(t *testing.T) {
+	s, _, session := newTestService(t, replyText)
+
+	const senders = 8
+	var wg sync.WaitGroup
+	done := make(chan models.MessageStatus, senders)
+	errs := make(chan error, senders)
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.SendMessage(context.Background(), session.ID, "hello", nil,
+				func(string, string) {}, func(models.ToolCall) {}, nil,
+				func(status models.MessageStatus) { done <- status })
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	started := 0
+	for err := range errs {
+		if err == nil {
+			started++
+		}
+	}
+	if started != 1 {
+		t.Fatalf("%d sends started, want exactly 1", started)
+	}
+	if status := <-done; status != models.StatusCompleted {
+		t.Errorf("status = %s", status)
+	}
+	if s.Generating(session.ID) {
+		t.Error("generation still registered after it finished")
+	}
+}
+
+func TestCloseWaitsForReplies(t *testing.T) {
+	requested := make(chan struct{})
+	s, store, session := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		close(requested)
+		<-r.Context().Done()
+	})
+
+	if err := s.SendMessage(context.Background(), session.ID, "hello", nil,
+		func(string, string) {}, func(models.ToolCall) {}, nil, func(models.MessageStatus) {}); err != nil {
+		t.Fatal(err)
+	}
+	<-requested
+	s.Close()
+
+	// The reply was saved before Close returned.
+	messages, err := store.ListMessages(session.ID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Role != models.RoleAssistant || messages[0].Status != models.StatusCancelled {
+		t.Fatalf("messages = %+v, want a cancelled reply", messages)
+	}
+	if err := s.SendMessage(context.Background(), session.ID, "again", nil,
+		func(string, string) {}, func(models.ToolCall) {}, nil, func(models.MessageStatus) {}); err == nil {
+		t.Error("a reply started after Close")
+	}
+}
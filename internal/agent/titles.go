@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"axe-desktop/pkg/models"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const (
+	// titleTimeout and summaryTimeout bound the background calls so a slow
+	// provider cannot leave them running indefinitely.
+	titleTimeout   = 30 * time.Second
+	summaryTimeout = 60 * time.Second
+
+	// maxTranscriptMessages and maxTranscriptRunes keep the prompts small;
+	// a title or summary does not need the whole conversation.
+	maxTranscriptMessages = 20
+	maxTranscriptRunes    = 1000
+
+	maxTitleRunes   = 60
+	maxSummaryRunes = 200
+)
+
+const titleInstruction = `You write titles for chat conversations. Reply with a concise title of at most six words that describes the topic. Use the language of the conversation. Reply with the title only: no quotes, no trailing punctuation, no prefix.`
+
+const summaryInstruction = `You summarize chat conversations. Reply with one sentence of at most 25 words describing what the conversation is about and where it stands. Reply with the sentence only.`
+
+// GenerateTitle asks the session's model for a short title based on the
+// opening exchange and stores it.
+func (s *Service) GenerateTitle(ctx context.Context, sessionID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+
+	lines, err := s.transcript(sessionID, maxTranscriptRunes)
+	if err != nil {
+		return "", err
+	}
+	// The opening exchange says best what the chat is about.
+	if len(lines) > 4 {
+		lines = lines[:4]
+	}
+	text, err := s.complete(ctx, sessionID, titleInstruction, strings.Join(lines, "\n\n"))
+	if err != nil {
+		return "", err
+	}
+
+	title := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(strings.TrimSpace(title), `"'*#.`)
+	if title == "" {
+		return "", fmt.Errorf("model returned an empty title")
+	}
+	title = truncateRunes(title, maxTitleRunes)
+
+	if err := s.storage.UpdateSessionTitle(sessionID, title); err != nil {
+		return "", err
+	}
+	return title, nil
+}
+
+// GenerateSummary asks the session's model for a one-line summary of the
+// recent conversation and stores it.
+func (s *Service) GenerateSummary(ctx context.Context, sessionID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+
+	lines, err := s.transcript(sessionID, maxTranscriptRunes)
+	if err != nil {
+		return "", err
+	}
+	if len(lines) > maxTranscriptMessages {
+		lines = lines[len(lines)-maxTranscriptMessages:]
+	}
+	text, err := s.complete(ctx, sessionID, summaryInstruction, strings.Join(lines, "\n\n"))
+	if err != nil {
+		return "", err
+	}
+
+	summary := strings.Join(strings.Fields(text), " ")
+	if summary == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	summary = truncateRunes(summary, maxSummaryRunes)
+
+	if err := s.storage.UpdateSessionSummary(sessionID, summary); err != nil {
+		return "", err
+	}
+	return summary, nil
+}
+
+// transcript renders the session's user and assistant messages as plain
+// text lines, oldest first, each cut to maxRunes.
+func (s *Service) transcript(sessionID string, maxRunes int) ([]string, error) {
+	messages, err := s.storage.ListMessages(sessionID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	lines := transcriptLines(messages, nil, maxRunes)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("session has no messages")
+	}
+	return lines, nil
+}
+
+// transcriptLines renders messages, given newest first, as text lines oldest
+// first. Messages at or before since are skipped.
+func transcriptLines(messages []models.Message, since *time.Time, maxRunes int) []string {
+	var lines []string
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Content == "" || (since != nil && !msg.CreatedAt.After(*since)) {
+			continue
+		}
+		var speaker string
+		switch msg.Role {
+		case models.RoleUser:
+			speaker = "User"
+		case models.RoleAssistant:
+			speaker = "Assistant"
+		default:
+			continue
+		}
+		lines = append(lines, speaker+": "+truncateRunes(msg.Content, maxRunes))
+	}
+	return lines
+}
+
+// complete makes a single, tool-less request to the session's model and
+// returns the text of the reply.
+func (s *Service) complete(ctx context.Context, sessionID, instruction, prompt string) (string, error) {
+	cfg, err := s.resolveRunnerConfig(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if cfg.provider.APIKey == "" && cfg.provider.Type != models.ProviderOpenAI {
+		return "", fmt.Errorf("no API key configured for provider %s", cfg.provider.Name)
+	}
+
+	llm, err := newModel(ctx, &cfg.provider)
+	if err != nil {
+		return "", fmt.Errorf("failed to create model: %w", err)
+	}
+
+	req := &model.LLMRequest{
+		Model:    cfg.provider.Model,
+		Contents: []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(instruction, genai.RoleUser),
+		},
+	}
+
+	var text strings.Builder
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", err
+		}
+		if resp.ErrorCode != "" {
+			return "", fmt.Errorf("%s: %s", resp.ErrorCode, resp.ErrorMessage)
+		}
+		if resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			if part.Text != "" && !part.Thought {
+				text.WriteString(part.Text)
+			}
+		}
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"axe-desktop/internal/storage"
+	"axe-desktop/pkg/models"
+
+	"google.golang.org/genai"
+)
+
+// toolCallRecorder persists the tool invocations of a single assistant
+// message, pairing each FunctionCall with its FunctionResponse.
+type toolCallRecorder struct {
+	storage   *storage.Storage
+	sessionID string
+	messageID string
+	pending   []*pendingToolCall
+}
+
+type pendingToolCall struct {
+	callID  string
+	call    *models.ToolCall
+	started time.Time
+}
+
+func newToolCallRecorder(store *storage.Storage, sessionID, messageID string) *toolCallRecorder {
+	return &toolCallRecorder{
+		storage:   store,
+		sessionID: sessionID,
+		messageID: messageID,
+	}
+}
+
+func (r *toolCallRecorder) start(fc *genai.FunctionCall) *models.ToolCall {
+	tc := &models.ToolCall{
+		SessionID: r.sessionID,
+		MessageID: r.messageID,
+		ToolName:  fc.Name,
+		Args:      fc.Args,
+	}
+	if err := r.storage.CreateToolCall(tc); err != nil {
+		fmt.Printf("[Agent] failed to record tool call %s: %v\n", fc.Name, err)
+	}
+
+	r.pending = append(r.pending, &pendingToolCall{
+		callID:  fc.ID,
+		call:    tc,
+		started: time.Now(),
+	})
+	return tc
+}
+
+// recordedID returns the ID of the stored record for a pending call, or "".
+func (r *toolCallRecorder) recordedID(callID string) string {
+	for _, p := range r.pending {
+		if callID != "" && p.callID == callID {
+			return p.call.ID
+		}
+	}
+	return ""
+}
+
+// finish records the response for the matching pending call. Calls are matched
+// by ID, falling back to the oldest pending call with the same tool name for
+// models that do not send call IDs.
+func (r *toolCallRecorder) finish(fr *genai.FunctionResponse) *models.ToolCall {
+	idx := -1
+	for i, p := range r.pending {
+		if fr.ID != "" && p.callID == fr.ID {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		for i, p := range r.pending {
+			if p.call.ToolName == fr.Name {
+				idx = i
+				break
+			}
+		}
+	}
+
+	var p *pendingToolCall
+	if idx == -1 {
+		// Response without a recorded call; store it on its own.
+		p = &pendingToolCall{call: &models.ToolCall{
+			SessionID: r.sessionID,
+			MessageID: r.messageID,
+			ToolName:  fr.Name,
+		}, started: time.Now()}
+		if err := r.storage.CreateToolCall(p.call); err != nil {
+			fmt.Printf("[Agent] failed to record tool call %s: %v\n", fr.Name, err)
+		}
+	} else {
+		p = r.pending[idx]
+		r.pending = append(r.pending[:idx], r.pending[idx+1:]...)
+	}
+
+	tc := p.call
+	tc.Result = fr.Response
+	if errMsg := toolError(fr.Response); errMsg != "" {
+		tc.Error = &errMsg
+	}
+	duration := time.Since(p.started).Milliseconds()
+	tc.DurationMs = &duration
+
+	if err := r.storage.UpdateToolCall(tc); err != nil {
+		fmt.Printf("[Agent] failed to update tool call %s: %v\n", tc.ToolName, err)
+	}
+	return tc
+}
+
+// abandon marks calls that never received a response, e.g. because the
+// generation was cancelled or failed.
+func (r *toolCallRecorder) abandon(reason string) []*models.ToolCall {
+	var abandoned []*models.ToolCall
+	for _, p := range r.pending {
+		tc := p.call
+		msg := reason
+		tc.Error = &msg
+		duration := time.Since(p.started).Milliseconds()
+		tc.DurationMs = &duration
+		if err := r.storage.UpdateToolCall(tc); err != nil {
+			fmt.Printf("[Agent] failed to update tool call %s: %v\n", tc.ToolName, err)
+		}
+		abandoned = append(abandoned, tc)
+	}
+	r.pending = nil
+	return abandoned
+}
+
+// toolError extracts the error ADK reports as {"error": "..."} when a tool fails.
+func toolError(response map[string]any) string {
+	if len(response) != 1 {
+		return ""
+	}
+	if msg, ok := response["error"].(string); ok {
+		return msg
+	}
+	return ""
+}
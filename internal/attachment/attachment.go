@@ -0,0 +1,170 @@
+// Package attachment stores files attached to messages and turns them into
+// model input.
+package attachment
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"axe-desktop/pkg/models"
+
+	"github.com/google/uuid"
+	"google.golang.org/genai"
+)
+
+const (
+	// MaxSize is the largest file that can be attached.
+	MaxSize = 20 << 20
+	// maxTextSize caps how much of a text file is sent to the model.
+	maxTextSize = 256 << 10
+)
+
+// Import copies a file into dir and returns the attachment describing it.
+// The attachment is not linked to a message until it is sent.
+func Import(dir, path string) (models.Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	if info.IsDir() {
+		return models.Attachment{}, fmt.Errorf("%s is a folder", filepath.Base(path))
+	}
+	if info.Size() > MaxSize {
+		return models.Attachment{}, fmt.Errorf("%s is larger than %d MB", filepath.Base(path), MaxSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	return Save(dir, filepath.Base(path), data)
+}
+
+// Save writes data into dir under a new ID, keeping name as the display name.
+func Save(dir, name string, data []byte) (models.Attachment, error) {
+	if len(data) > MaxSize {
+		return models.Attachment{}, fmt.Errorf("%s is larger than %d MB", name, MaxSize>>20)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return models.Attachment{}, err
+	}
+
+	mimeType := detectMIME(name, data)
+	a := models.Attachment{
+		ID:   uuid.New().String(),
+		Type: models.AttachmentFile,
+		Metadata: map[string]any{
+			"name": name,
+			"mime": mimeType,
+			"size": len(data),
+		},
+	}
+	if strings.HasPrefix(mimeType, "image/") {
+		a.Type = models.AttachmentImage
+	}
+	a.Path = filepath.Join(dir, a.ID+strings.ToLower(filepath.Ext(name)))
+
+	if err := os.WriteFile(a.Path, data, 0600); err != nil {
+		return models.Attachment{}, err
+	}
+	return a, nil
+}
+
+// Remove deletes the attachment's file.
+func Remove(a models.Attachment) error {
+	err := os.Remove(a.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Name is the file name the attachment was added with.
+func Name(a models.Attachment) string {
+	if name, ok := a.Metadata["name"].(string); ok && name != "" {
+		return name
+	}
+	return filepath.Base(a.Path)
+}
+
+// MIMEType is the media type recorded for the attachment.
+func MIMEType(a models.Attachment) string {
+	if m, ok := a.Metadata["mime"].(string); ok && m != "" {
+		return m
+	}
+	return detectMIME(a.Path, nil)
+}
+
+// Part converts an attachment into model input. Images and other binary
+// files are sent inline; text files are sent as text so every provider can
+// read them.
+func Part(a models.Attachment) (*genai.Part, error) {
+	data, err := os.ReadFile(a.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment %s: %w", Name(a), err)
+	}
+
+	mimeType := MIMEType(a)
+	if a.Type != models.AttachmentImage && isText(mimeType, data) {
+		text := string(data)
+		truncated := ""
+		if len(text) > maxTextSize {
+			end := maxTextSize
+			for end > 0 && !utf8.RuneStart(text[end]) {
+				end--
+			}
+			text = text[:end]
+			truncated = "\n[truncated]"
+		}
+		f := fence(text)
+		return genai.NewPartFromText(fmt.Sprintf("Attached file %s:\n\n%s\n%s%s\n%s", Name(a), f, text, truncated, f)), nil
+	}
+	return genai.NewPartFromBytes(data, mimeType), nil
+}
+
+// fence returns a code fence longer than any run of backticks in text, so
+// the text cannot close it early.
+func fence(text string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func detectMIME(name string, data []byte) string {
+	if m := mime.TypeByExtension(strings.ToLower(filepath.Ext(name))); m != "" {
+		m, _, _ = strings.Cut(m, ";")
+		return m
+	}
+	if data != nil {
+		m, _, _ := strings.Cut(http.DetectContentType(data), ";")
+		return m
+	}
+	return "application/octet-stream"
+}
+
+func isText(mimeType string, data []byte) bool {
+	switch {
+	case strings.HasPrefix(mimeType, "text/"),
+		mimeType == "application/json",
+		mimeType == "application/xml",
+		mimeType == "application/javascript",
+		mimeType == "application/x-yaml",
+		mimeType == "application/toml":
+		return utf8.Valid(data)
+	case mimeType == "application/octet-stream":
+		// Source files with unknown extensions are usually plain text.
+		return utf8.Valid(data) && !strings.ContainsRune(string(data), 0)
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package attachment
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"axe-desktop/pkg/models"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestDetectMIME(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"photo.PNG", nil, "image/png"},
+		{"notes.txt", []byte("hello"), "text/plain"},
+		{"data.json", nil, "application/json"},
+		{"image", pngHeader, "image/png"},
+		{"script.zzq", []byte("plain words"), "text/plain"},
+		{"unknown", nil, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := detectMIME(tt.name, tt.data); got != tt.want {
+			t.Errorf("detectMIME(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		mime string
+		data string
+		want bool
+	}{
+		{"text/plain", "hello", true},
+		{"text/plain", "\xff\xfe", false},
+		{"application/json", `{"a": 1}`, true},
+		{"application/octet-stream", "package main", true},
+		{"application/octet-stream", "bin\x00ary", false},
+		{"image/png", "hello", false},
+	}
+	for _, tt := range tests {
+		if got := isText(tt.mime, []byte(tt.data)); got != tt.want {
+			t.Errorf("isText(%q, %q) = %v, want %v", tt.mime, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestPart(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		// text checks the text of a text part; a nil text expects the
+		// data inline.
+		text func(t *testing.T, text string)
+	}{
+		{"text", "notes.txt", "hello", func(t *testing.T, text string) {
+			if text != "Attached file notes.txt:\n\n```\nhello\n```" {
+				t.Errorf("text = %q", text)
+			}
+		}},
+		{"backticks", "README.md", "Run:\n```sh\nmake\n```\n", func(t *testing.T, text string) {
+			if !strings.HasPrefix(text, "Attached file README.md:\n\n````\n") || !strings.HasSuffix(text, "\n````") {
+				t.Errorf("text = %q, want a four-backtick fence", text)
+			}
+		}},
+		{"truncated", "big.txt", strings.Repeat("é", maxTextSize), func(t *testing.T, text string) {
+			if !utf8.ValidString(text) {
+				t.Error("truncated text is not valid UTF-8")
+			}
+			if !strings.HasSuffix(text, "é\n[truncated]\n```") {
+				t.Errorf("text ends with %q", text[len(text)-30:])
+			}
+		}},
+		{"image", "photo.png", string(pngHeader), nil},
+		{"binary", "blob.bin", "bin\x00ary", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Save(t.TempDir(), tt.file, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			part, err := Part(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.text == nil {
+				if part.InlineData == nil || string(part.InlineData.Data) != tt.data {
+					t.Fatalf("part = %+v, want the data inline", part)
+				}
+				if a.Type == models.AttachmentImage && part.InlineData.MIMEType != "image/png" {
+					t.Errorf("mime = %q", part.InlineData.MIMEType)
+				}
+				return
+			}
+			if part.InlineData != nil {
+				t.Fatalf("part is inline data, want text")
+			}
+			tt.text(t, part.Text)
+		})
+	}
+}
+
+func TestFence(t *testing.T) {
+	tests := map[string]string{
+		"":             "```",
+		"a `b` c":      "```",
+		"```go\n```":   "````",
+		"x ````` y ``": "``````",
+	}
+	for text, want := range tests {
+		if got := fence(text); got != want {
+			t.Errorf("fence(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package clipboard reads images from the system clipboard, which Fyne only
+// exposes as text. Each platform asks a standard tool for the image data.
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os/exec"
+	"time"
+)
+
+// ErrNoImage is returned when the clipboard does not hold an image.
+var ErrNoImage = errors.New("clipboard does not contain an image")
+
+// readTimeout bounds how long the clipboard tool may take.
+const readTimeout = 5 * time.Second
+
+// ReadImage returns the clipboard image encoded as PNG.
+func ReadImage() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+
+	data, err := readImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrNoImage
+	}
+	return toPNG(data)
+}
+
+// toPNG re-encodes image data as PNG unless it already is one.
+func toPNG(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrNoImage
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// output runs a clipboard tool and returns its standard output.
+func output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is needed to paste images: %w", name, err)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return nil, fmt.Errorf("%s: %s", name, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
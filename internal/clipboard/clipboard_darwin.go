@@ -0,0 +1,25 @@
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+)
+
+// readImage asks AppleScript for the clipboard as PNG, which it prints as
+// «data PNGf89504E47...».
+func readImage(ctx context.Context) ([]byte, error) {
+	out, err := output(ctx, "osascript", "-e", "the clipboard as «class PNGf»")
+	if err != nil {
+		// osascript fails when the clipboard cannot be converted to PNG.
+		return nil, ErrNoImage
+	}
+	out = bytes.TrimSpace(out)
+	out = bytes.TrimPrefix(out, []byte("«data PNGf"))
+	out = bytes.TrimSuffix(out, []byte("»"))
+	data, err := hex.DecodeString(string(out))
+	if err != nil {
+		return nil, ErrNoImage
+	}
+	return data, nil
+}
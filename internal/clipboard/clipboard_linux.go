@@ -0,0 +1,62 @@
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// imageTypes are the clipboard formats tried, in order of preference.
+var imageTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// readImage uses wl-paste on Wayland and xclip on X11.
+func readImage(ctx context.Context) ([]byte, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		types, err := output(ctx, "wl-paste", "--list-types")
+		if err != nil {
+			return nil, missingTool(err)
+		}
+		mimeType, ok := pickType(string(types))
+		if !ok {
+			return nil, ErrNoImage
+		}
+		return output(ctx, "wl-paste", "--no-newline", "--type", mimeType)
+	}
+
+	targets, err := output(ctx, "xclip", "-selection", "clipboard", "-t", "TARGETS", "-o")
+	if err != nil {
+		return nil, missingTool(err)
+	}
+	mimeType, ok := pickType(string(targets))
+	if !ok {
+		return nil, ErrNoImage
+	}
+	return output(ctx, "xclip", "-selection", "clipboard", "-t", mimeType, "-o")
+}
+
+// missingTool reports a missing clipboard tool; any other failure means
+// there is nothing to paste, as both tools exit with an error when the
+// clipboard is empty.
+func missingTool(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return err
+	}
+	return ErrNoImage
+}
+
+// pickType chooses an image format from the newline-separated list offered
+// by the clipboard owner.
+func pickType(list string) (string, bool) {
+	offered := map[string]bool{}
+	for _, t := range strings.Fields(list) {
+		offered[t] = true
+	}
+	for _, t := range imageTypes {
+		if offered[t] {
+			return t, true
+		}
+	}
+	return "", false
+}
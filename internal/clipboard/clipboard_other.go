@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package clipboard
+
+import "context"
+
+func readImage(context.Context) ([]byte, error) {
+	return nil, ErrNoImage
+}
@@ -0,0 +1,28 @@
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+)
+
+const readScript = `Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$img = [System.Windows.Forms.Clipboard]::GetImage()
+if ($img) {
+	$ms = New-Object System.IO.MemoryStream
+	$img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png)
+	[Convert]::ToBase64String($ms.ToArray())
+}`
+
+// readImage asks PowerShell for the clipboard image, saved as base64 PNG.
+func readImage(ctx context.Context) ([]byte, error) {
+	out, err := output(ctx, "powershell", "-NoProfile", "-NonInteractive", "-STA", "-Command", readScript)
+	if err != nil {
+		return nil, err
+	}
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, ErrNoImage
+	}
+	return base64.StdEncoding.DecodeString(string(out))
+}
@@ -11,11 +11,25 @@ import (
 
 type Config struct {
 	DBPath           string             `json:"db_path"`
+	LogDir           string             `json:"log_dir"`
+	AttachmentDir    string             `json:"attachment_dir"`
 	Providers        []models.Provider  `json:"providers"`
 	MCPServers       []models.MCPServer `json:"mcp_servers"`
 	ActiveProviderID string             `json:"active_provider_id"`
+	// Prices is the price table used to estimate the cost of token usage.
+	Prices []models.ModelPrice `json:"prices,omitempty"`
+
+	// baseURLOverride is the OPENAI_BASE_URL applied to the built-in OpenAI
+	// provider, and savedBaseURL the value it replaced; Save writes the
+	// latter so the override is never persisted.
+	baseURLOverride string
+	savedBaseURL    string
 }
 
+// defaultOpenAIProviderID is the built-in OpenAI-compatible provider, the
+// only one OPENAI_API_KEY and OPENAI_BASE_URL apply to.
+const defaultOpenAIProviderID = "default-openai"
+
 func Load() (*Config, error) {
 	_ = godotenv.Load()
 
@@ -30,16 +44,10 @@ func Load() (*Config, error) {
 	}
 
 	cfg := &Config{
-		DBPath: filepath.Join(configDir, "axe-desktop.db"),
-		Providers: []models.Provider{
-			{
-				ID:      "default-gemini",
-				Name:    "Google Gemini",
-				Type:    models.ProviderGemini,
-				Model:   "gemini-2.0-flash",
-				Enabled: true,
-			},
-		},
+		DBPath:        filepath.Join(configDir, "axe-desktop.db"),
+		LogDir:        filepath.Join(configDir, "logs"),
+		AttachmentDir: filepath.Join(configDir, "attachments"),
+		Providers:     builtinProviders(),
 		MCPServers: []models.MCPServer{
 			{
 				ID:      "exa",
@@ -56,6 +64,13 @@ func Load() (*Config, error) {
 	if data, err := os.ReadFile(configPath); err == nil {
 		_ = json.Unmarshal(data, cfg)
 	}
+	// A saved provider list replaces the built-in one; add back any built-in
+	// provider it lacks so the environment overrides below have a target.
+	for _, p := range builtinProviders() {
+		if cfg.GetProvider(p.ID) == nil {
+			cfg.Providers = append(cfg.Providers, p)
+		}
+	}
 
 	// Environment variable overrides for BYOK convenience
 	googleKey := os.Getenv("GOOGLE_API_KEY")
@@ -63,15 +78,50 @@ func Load() (*Config, error) {
 		googleKey = os.Getenv("AXE_API_KEY")
 	}
 
+	openaiKey := os.Getenv("OPENAI_API_KEY")
+	openaiBaseURL := os.Getenv("OPENAI_BASE_URL")
+
 	for i := range cfg.Providers {
 		if cfg.Providers[i].Type == models.ProviderGemini && googleKey != "" {
 			cfg.Providers[i].APIKey = googleKey
 		}
+		if cfg.Providers[i].ID != defaultOpenAIProviderID {
+			continue
+		}
+		if openaiKey != "" {
+			cfg.Providers[i].APIKey = openaiKey
+		}
+		if openaiBaseURL != "" {
+			cfg.savedBaseURL = cfg.Providers[i].BaseURL
+			cfg.baseURLOverride = openaiBaseURL
+			cfg.Providers[i].BaseURL = openaiBaseURL
+		}
 	}
 
 	return cfg, nil
 }
 
+// builtinProviders returns the providers every configuration starts with.
+func builtinProviders() []models.Provider {
+	return []models.Provider{
+		{
+			ID:      "default-gemini",
+			Name:    "Google Gemini",
+			Type:    models.ProviderGemini,
+			Model:   "gemini-2.0-flash",
+			Enabled: true,
+		},
+		{
+			ID:      defaultOpenAIProviderID,
+			Name:    "OpenAI Compatible",
+			Type:    models.ProviderOpenAI,
+			BaseURL: "https://api.openai.com/v1",
+			Model:   "gpt-4o-mini",
+			Enabled: true,
+		},
+	}
+}
+
 func (c *Config) Save() error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -81,7 +131,18 @@ func (c *Config) Save() error {
 	configDir := filepath.Join(homeDir, ".axe-desktop")
 	configPath := filepath.Join(configDir, "config.json")
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	saved := *c
+	if c.baseURLOverride != "" {
+		saved.Providers = append([]models.Provider(nil), c.Providers...)
+		for i := range saved.Providers {
+			// A URL edited in settings replaces the override and is kept.
+			if saved.Providers[i].ID == defaultOpenAIProviderID && saved.Providers[i].BaseURL == c.baseURLOverride {
+				saved.Providers[i].BaseURL = c.savedBaseURL
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(&saved, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -89,12 +150,19 @@ func (c *Config) Save() error {
 	return os.WriteFile(configPath, data, 0600)
 }
 
-func (c *Config) GetActiveProvider() *models.Provider {
+func (c *Config) GetProvider(id string) *models.Provider {
 	for i := range c.Providers {
-		if c.Providers[i].ID == c.ActiveProviderID {
+		if c.Providers[i].ID == id {
 			return &c.Providers[i]
 		}
 	}
+	return nil
+}
+
+func (c *Config) GetActiveProvider() *models.Provider {
+	if p := c.GetProvider(c.ActiveProviderID); p != nil {
+		return p
+	}
 	if len(c.Providers) > 0 {
 		return &c.Providers[0]
 	}
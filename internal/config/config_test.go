@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"axe-desktop/pkg/models"
+)
+
+// writeConfig stores a config.json under a temporary home directory.
+func writeConfig(t *testing.T, data string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, key := range []string{"GOOGLE_API_KEY", "AXE_API_KEY", "OPENAI_API_KEY", "OPENAI_BASE_URL"} {
+		t.Setenv(key, "")
+	}
+	dir := filepath.Join(home, ".axe-desktop")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeepsBuiltinProviders(t *testing.T) {
+	writeConfig(t, `{"providers": [{"id": "local", "type": "openai", "base_url": "http://localhost:8080/v1", "api_key": "local-key"}]}`)
+	t.Setenv("OPENAI_API_KEY", "env-key")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"local", "default-gemini", defaultOpenAIProviderID} {
+		if cfg.GetProvider(id) == nil {
+			t.Errorf("provider %q missing", id)
+		}
+	}
+	if cfg.Providers[0].ID != "local" {
+		t.Errorf("first provider = %q, want the saved one", cfg.Providers[0].ID)
+	}
+	if got := cfg.GetProvider("local").APIKey; got != "local-key" {
+		t.Errorf("local API key = %q, want the saved key", got)
+	}
+	if got := cfg.GetProvider(defaultOpenAIProviderID).APIKey; got != "env-key" {
+		t.Errorf("built-in OpenAI API key = %q, want OPENAI_API_KEY", got)
+	}
+}
+
+func TestLoadKeepsSavedBuiltinProvider(t *testing.T) {
+	writeConfig(t, `{"providers": [{"id": "default-openai", "type": "openai", "model": "gpt-4o"}]}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Providers) != 2 {
+		t.Fatalf("providers = %+v, want the saved one and Gemini", cfg.Providers)
+	}
+	p := cfg.GetProvider(defaultOpenAIProviderID)
+	if p.Model != "gpt-4o" || p.Type != models.ProviderOpenAI {
+		t.Errorf("built-in OpenAI provider = %+v, want the saved settings", p)
+	}
+}
@@ -0,0 +1,197 @@
+// Package export writes a session and everything recorded for it to
+// Markdown, JSON or a self-contained HTML page.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"axe-desktop/internal/storage"
+	"axe-desktop/pkg/models"
+)
+
+// FormatVersion is written to JSON exports so importers can detect the
+// document layout.
+const FormatVersion = 1
+
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+	FormatHTML     Format = "html"
+)
+
+// Formats lists the export formats in menu order.
+var Formats = []Format{FormatMarkdown, FormatJSON, FormatHTML}
+
+func (f Format) Extension() string {
+	switch f {
+	case FormatMarkdown:
+		return ".md"
+	case FormatHTML:
+		return ".html"
+	default:
+		return ".json"
+	}
+}
+
+func (f Format) Label() string {
+	switch f {
+	case FormatMarkdown:
+		return "Markdown"
+	case FormatHTML:
+		return "HTML"
+	default:
+		return "JSON"
+	}
+}
+
+// Conversation is a session with its messages in chronological order, the
+// tool calls and attachments that belong to them, and export metadata. Its
+// JSON encoding is the lossless export format.
+type Conversation struct {
+	FormatVersion int                 `json:"format_version"`
+	ExportedAt    time.Time           `json:"exported_at"`
+	Session       models.Session      `json:"session"`
+	Messages      []models.Message    `json:"messages"`
+	ToolCalls     []models.ToolCall   `json:"tool_calls"`
+	Attachments   []models.Attachment `json:"attachments"`
+}
+
+// Load reads a session and everything recorded for it from storage.
+func Load(store *storage.Storage, sessionID string) (*Conversation, error) {
+	session, err := store.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := store.ListMessages(sessionID, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+	// ListMessages returns the newest first.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	toolCalls, err := store.ListToolCalls(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tool calls: %w", err)
+	}
+	for i, j := 0, len(toolCalls)-1; i < j; i, j = i+1, j-1 {
+		toolCalls[i], toolCalls[j] = toolCalls[j], toolCalls[i]
+	}
+
+	attachments, err := store.ListAttachments(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+
+	return &Conversation{
+		FormatVersion: FormatVersion,
+		ExportedAt:    time.Now(),
+		Session:       *session,
+		Messages:      messages,
+		ToolCalls:     toolCalls,
+		Attachments:   attachments,
+	}, nil
+}
+
+// Write encodes the conversation in the given format.
+func Write(w io.Writer, conv *Conversation, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		return WriteMarkdown(w, conv)
+	case FormatHTML:
+		return WriteHTML(w, conv)
+	case FormatJSON:
+		return WriteJSON(w, conv)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func WriteJSON(w io.Writer, conv *Conversation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(conv)
+}
+
+// FileName suggests a file name for the export based on the session title.
+func FileName(session models.Session, format Format) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ' || r == '-' || r == '_':
+			return '-'
+		case strings.ContainsRune(`/\:*?"<>|.`, r), r < 32:
+			return -1
+		default:
+			return r
+		}
+	}, strings.TrimSpace(session.Title))
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = "chat"
+	}
+	if len([]rune(name)) > 60 {
+		name = string([]rune(name)[:60])
+	}
+	return name + format.Extension()
+}
+
+// toolCallsByMessage groups tool calls under the message that made them.
+func (c *Conversation) toolCallsByMessage() map[string][]models.ToolCall {
+	grouped := make(map[string][]models.ToolCall)
+	for _, tc := range c.ToolCalls {
+		grouped[tc.MessageID] = append(grouped[tc.MessageID], tc)
+	}
+	return grouped
+}
+
+func (c *Conversation) attachmentsByMessage() map[string][]models.Attachment {
+	grouped := make(map[string][]models.Attachment)
+	for _, a := range c.Attachments {
+		grouped[a.MessageID] = append(grouped[a.MessageID], a)
+	}
+	return grouped
+}
+
+func roleLabel(role models.MessageRole) string {
+	switch role {
+	case models.RoleUser:
+		return "You"
+	case models.RoleAssistant:
+		return "Assistant"
+	case models.RoleTool:
+		return "Tool"
+	default:
+		return "System"
+	}
+}
+
+func indentJSON(v map[string]any) string {
+	if len(v) == 0 {
+		return "{}"
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// attachmentName is the display name of an attachment, preferring the
+// original file name recorded in its metadata.
+func attachmentName(a models.Attachment) string {
+	if name, ok := a.Metadata["name"].(string); ok && name != "" {
+		return name
+	}
+	if i := strings.LastIndexAny(a.Path, `/\`); i >= 0 {
+		return a.Path[i+1:]
+	}
+	return a.Path
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"axe-desktop/internal/storage"
+	"axe-desktop/pkg/models"
+)
+
+// loadFixture stores the conversation in testdata/conversation.json and
+// returns it as read from the file.
+func loadFixture(t *testing.T) (*storage.Storage, *Conversation, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "conversation.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conv Conversation
+	if err := json.Unmarshal(data, &conv); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	session := conv.Session
+	if err := store.CreateSession(&session); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range conv.Messages {
+		if err := store.CreateMessage(&msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range conv.ToolCalls {
+		if err := store.CreateToolCall(&tc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, a := range conv.Attachments {
+		if err := store.CreateAttachment(&a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store, &conv, data
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	store, fixture, data := loadFixture(t)
+
+	conv, err := Load(store, fixture.Session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv.ExportedAt = fixture.ExportedAt
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, conv); err != nil {
+		t.Fatal(err)
+	}
+
+	var got, want any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("export differs from the stored fixture:\n%s", buf.String())
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	_, conv, _ := loadFixture(t)
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, conv); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Fix the build\n",
+		"- Model: gpt-4o\n",
+		"> You are a careful Go reviewer.",
+		"> The user asked why the build fails.",
+		"Attachment: [build.log](</attachments/build.log>)",
+		"<summary>Tool: read_file</summary>",
+		"\"path\": \"testdata/input.txt\"",
+		"The test reads a **missing** file.",
+		"_Failed: rate limited_",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown is missing %q:\n%s", want, out)
+		}
+	}
+	// The tool call is shown with the message that made it.
+	if strings.Index(out, "Tool: read_file") > strings.Index(out, "The test reads") {
+		t.Error("tool call is not listed under its message")
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	_, conv, _ := loadFixture(t)
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, conv); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<script>alert(1)</script>") {
+		t.Error("message HTML was not escaped")
+	}
+	for _, want := range []string{"Fix the build", "&lt;script&gt;", "<strong>missing</strong>", "read_file", "Failed: rate limited"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML is missing %q", want)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Fix the build", "Fix-the-build.md"},
+		{`a/b\c: "d"?`, "abc-d.md"},
+		{"  ...  ", "chat.md"},
+		{strings.Repeat("é", 70), strings.Repeat("é", 60) + ".md"},
+	}
+	for _, tt := range tests {
+		if got := FileName(models.Session{Title: tt.title}, FormatMarkdown); got != tt.want {
+			t.Errorf("FileName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
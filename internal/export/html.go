@@ -0,0 +1,196 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"axe-desktop/pkg/models"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders message content. Raw HTML in messages is not passed
+// through, so exported pages cannot run scripts from model output.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// maxInlineImage caps the size of images embedded into HTML exports.
+const maxInlineImage = 10 << 20
+
+type htmlMessage struct {
+	Role        string
+	Label       string
+	Time        string
+	Content     template.HTML
+	Status      string
+	ToolCalls   []htmlToolCall
+	Attachments []htmlAttachment
+}
+
+type htmlToolCall struct {
+	Name   string
+	Args   string
+	Result string
+	Error  string
+}
+
+type htmlAttachment struct {
+	Name    string
+	Path    string
+	DataURL template.URL
+}
+
+// WriteHTML writes the conversation as a single HTML page with inline styles
+// and images, so it can be opened or shared without other files.
+func WriteHTML(w io.Writer, conv *Conversation) error {
+	toolCalls := conv.toolCallsByMessage()
+	attachments := conv.attachmentsByMessage()
+
+	var messages []htmlMessage
+	for _, msg := range conv.Messages {
+		m := htmlMessage{
+			Role:  string(msg.Role),
+			Label: roleLabel(msg.Role),
+			Time:  msg.CreatedAt.Format(time.DateTime),
+		}
+		// Only assistant replies are Markdown; other text is shown verbatim,
+		// as in the app.
+		if msg.Role == models.RoleAssistant {
+			m.Content = renderMarkdown(msg.Content)
+		} else if msg.Content != "" {
+			m.Content = template.HTML(`<p class="plain">` + template.HTMLEscapeString(msg.Content) + `</p>`)
+		}
+		switch msg.Status {
+		case models.StatusCancelled:
+			m.Status = "Generation stopped."
+		case models.StatusFailed:
+			m.Status = "Failed."
+			if errMsg, ok := msg.Metadata["error"].(string); ok && errMsg != "" {
+				m.Status = "Failed: " + errMsg
+			}
+		}
+		for _, tc := range toolCalls[msg.ID] {
+			call := htmlToolCall{Name: tc.ToolName, Args: indentJSON(tc.Args)}
+			if tc.Result != nil {
+				call.Result = indentJSON(tc.Result)
+			}
+			if tc.Error != nil {
+				call.Error = *tc.Error
+			}
+			m.ToolCalls = append(m.ToolCalls, call)
+		}
+		for _, a := range attachments[msg.ID] {
+			m.Attachments = append(m.Attachments, htmlAttachment{
+				Name:    attachmentName(a),
+				Path:    a.Path,
+				DataURL: inlineImage(a.Path),
+			})
+		}
+		messages = append(messages, m)
+	}
+
+	data := struct {
+		Session  models.Session
+		Created  string
+		Exported string
+		Summary  string
+		Messages []htmlMessage
+	}{
+		Session:  conv.Session,
+		Created:  conv.Session.CreatedAt.Format(time.DateTime),
+		Exported: conv.ExportedAt.Format(time.DateTime),
+		Messages: messages,
+	}
+	if conv.Session.Summary != nil {
+		data.Summary = *conv.Session.Summary
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
+
+func renderMarkdown(content string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(content), &buf); err != nil {
+		return template.HTML("<pre>" + template.HTMLEscapeString(content) + "</pre>")
+	}
+	return template.HTML(buf.String())
+}
+
+// inlineImage returns a data URL for image attachments that can still be
+// read, or an empty URL for anything else.
+func inlineImage(path string) template.URL {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxInlineImage {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	mime := http.DetectContentType(data)
+	if !strings.HasPrefix(mime, "image/") {
+		return ""
+	}
+	return template.URL("data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+var htmlTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Session.Title}}</title>
+<style>
+body { margin: 0; background: #000; color: #fff; font: 15px/1.6 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; }
+main { max-width: 860px; margin: 0 auto; padding: 32px 16px; }
+h1 { font-size: 24px; margin: 0 0 8px; }
+.meta { color: #888; font-size: 13px; margin-bottom: 24px; }
+.prompt { border-left: 3px solid #333; padding: 4px 12px; color: #bbb; white-space: pre-wrap; margin-bottom: 24px; }
+.message { background: #111; border-radius: 8px; padding: 12px 16px; margin: 12px 0; }
+.message.user { background: #333; margin-left: 15%; }
+.message.system { color: #888; }
+.role { color: #888; font-size: 13px; font-weight: bold; }
+.role time { font-weight: normal; margin-left: 8px; }
+.status { color: #888; font-style: italic; }
+.plain { white-space: pre-wrap; }
+pre { background: #000; border: 1px solid #333; border-radius: 6px; padding: 10px; overflow-x: auto; }
+code { font-family: ui-monospace, Menlo, Consolas, monospace; font-size: 13px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #333; padding: 4px 8px; }
+a { color: #0070f3; }
+details { margin: 8px 0; color: #bbb; }
+summary { cursor: pointer; }
+.error { color: #ff5555; }
+.attachment img { max-width: 100%; border-radius: 6px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Session.Title}}</h1>
+<div class="meta">Created {{.Created}}{{if .Session.Model}} · {{.Session.Model}}{{end}} · Exported {{.Exported}}</div>
+{{if .Session.SystemPrompt}}<div class="prompt">{{.Session.SystemPrompt}}</div>{{end}}
+{{if .Summary}}<div class="prompt">{{.Summary}}</div>{{end}}
+{{range .Messages}}
+<section class="message {{.Role}}">
+<div class="role">{{.Label}}<time>{{.Time}}</time></div>
+{{range .Attachments}}<div class="attachment">{{if .DataURL}}<img src="{{.DataURL}}" alt="{{.Name}}">{{else}}Attachment: {{.Name}}{{end}}</div>
+{{end}}{{range .ToolCalls}}<details>
+<summary>Tool: {{.Name}}{{if .Error}} (failed){{end}}</summary>
+<pre><code>{{.Args}}</code></pre>
+{{if .Result}}<pre><code>{{.Result}}</code></pre>{{end}}
+{{if .Error}}<pre class="error"><code>{{.Error}}</code></pre>{{end}}
+</details>
+{{end}}{{.Content}}
+{{if .Status}}<p class="status">{{.Status}}</p>{{end}}
+</section>
+{{end}}
+</main>
+</body>
+</html>
+`))
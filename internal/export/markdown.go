@@ -0,0 +1,96 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"axe-desktop/pkg/models"
+)
+
+// WriteMarkdown writes the conversation as a readable Markdown document. Tool
+// calls are included as collapsible sections.
+func WriteMarkdown(w io.Writer, conv *Conversation) error {
+	bw := bufio.NewWriter(w)
+	session := conv.Session
+
+	fmt.Fprintf(bw, "# %s\n\n", session.Title)
+	fmt.Fprintf(bw, "- Created: %s\n", session.CreatedAt.Format(time.DateTime))
+	if session.Model != "" {
+		fmt.Fprintf(bw, "- Model: %s\n", session.Model)
+	}
+	fmt.Fprintf(bw, "- Exported: %s\n\n", conv.ExportedAt.Format(time.DateTime))
+	if session.SystemPrompt != "" {
+		fmt.Fprintf(bw, "**System prompt**\n\n%s\n\n", quote(session.SystemPrompt))
+	}
+	if session.Summary != nil && *session.Summary != "" {
+		fmt.Fprintf(bw, "**Summary**\n\n%s\n\n", quote(*session.Summary))
+	}
+
+	toolCalls := conv.toolCallsByMessage()
+	attachments := conv.attachmentsByMessage()
+
+	for _, msg := range conv.Messages {
+		fmt.Fprintf(bw, "---\n\n### %s · %s\n\n", roleLabel(msg.Role), msg.CreatedAt.Format(time.DateTime))
+
+		for _, a := range attachments[msg.ID] {
+			fmt.Fprintf(bw, "Attachment: [%s](<%s>)\n\n", attachmentName(a), a.Path)
+		}
+
+		for _, tc := range toolCalls[msg.ID] {
+			writeMarkdownToolCall(bw, tc)
+		}
+
+		if msg.Content != "" {
+			fmt.Fprintf(bw, "%s\n\n", strings.TrimRight(msg.Content, "\n"))
+		}
+
+		switch msg.Status {
+		case models.StatusCancelled:
+			bw.WriteString("_Generation stopped._\n\n")
+		case models.StatusFailed:
+			if errMsg, ok := msg.Metadata["error"].(string); ok && errMsg != "" {
+				fmt.Fprintf(bw, "_Failed: %s_\n\n", errMsg)
+			} else {
+				bw.WriteString("_Failed._\n\n")
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeMarkdownToolCall(w *bufio.Writer, tc models.ToolCall) {
+	summary := "Tool: " + tc.ToolName
+	if tc.Error != nil {
+		summary += " (failed)"
+	}
+	fmt.Fprintf(w, "<details>\n<summary>%s</summary>\n\n", summary)
+	fmt.Fprintf(w, "Arguments:\n\n%s\n\n", fenced(indentJSON(tc.Args), "json"))
+	if tc.Result != nil {
+		fmt.Fprintf(w, "Result:\n\n%s\n\n", fenced(indentJSON(tc.Result), "json"))
+	}
+	if tc.Error != nil {
+		fmt.Fprintf(w, "Error:\n\n%s\n\n", fenced(*tc.Error, ""))
+	}
+	w.WriteString("</details>\n\n")
+}
+
+// fenced wraps text in a code fence longer than any backtick run inside it.
+func fenced(text, lang string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+func quote(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
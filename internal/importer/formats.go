@@ -0,0 +1,259 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"axe-desktop/internal/export"
+	"axe-desktop/pkg/models"
+)
+
+// parseExport reads the lossless JSON written by the export package. IDs are
+// kept so tool calls stay linked to their messages and a session exported
+// from this database is recognised as a duplicate. Attachments are not
+// imported because their files are not part of the export.
+func parseExport(data []byte) ([]conversation, error) {
+	var exported export.Conversation
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("invalid export file: %w", err)
+	}
+	if exported.FormatVersion == 0 || exported.Session.ID == "" {
+		return nil, fmt.Errorf("unrecognised JSON file: not an axe-desktop export")
+	}
+	if exported.FormatVersion > export.FormatVersion {
+		return nil, fmt.Errorf("export format version %d is newer than supported version %d", exported.FormatVersion, export.FormatVersion)
+	}
+
+	session := exported.Session
+	session.ArchivedAt = nil
+	// The workspace is a folder on the exporting machine; file access is
+	// not carried over with an import.
+	session.Workspace = ""
+	sourceID := session.ID
+	if session.SourceID != "" {
+		sourceID = session.SourceID
+	}
+
+	return []conversation{{
+		sourceID: sourceID,
+		session:  session,
+		messages: exported.Messages,
+		calls:    exported.ToolCalls,
+	}}, nil
+}
+
+type chatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	UpdateTime     float64                `json:"update_time"`
+	CurrentNode    string                 `json:"current_node"`
+	DefaultModel   string                 `json:"default_model_slug"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Parent   string          `json:"parent"`
+	Children []string        `json:"children"`
+	Message  *chatGPTMessage `json:"message"`
+}
+
+type chatGPTMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// parseChatGPT reads ChatGPT's conversations.json. Each conversation is a
+// tree of edits and regenerations; the branch ending at current_node is the
+// one the user last saw, so only that branch is imported.
+func parseChatGPT(data []byte) ([]conversation, error) {
+	var items []chatGPTConversation
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid ChatGPT export: %w", err)
+	}
+
+	convs := make([]conversation, 0, len(items))
+	for _, item := range items {
+		id := item.ConversationID
+		if id == "" {
+			id = item.ID
+		}
+
+		var branch []*chatGPTMessage
+		seen := map[string]bool{}
+		for nodeID := item.CurrentNode; nodeID != "" && !seen[nodeID]; {
+			seen[nodeID] = true
+			node, ok := item.Mapping[nodeID]
+			if !ok {
+				break
+			}
+			if node.Message != nil {
+				branch = append(branch, node.Message)
+			}
+			nodeID = node.Parent
+		}
+
+		var messages []models.Message
+		for i := len(branch) - 1; i >= 0; i-- {
+			msg := branch[i]
+			if hidden, _ := msg.Metadata["is_visually_hidden_from_conversation"].(bool); hidden {
+				continue
+			}
+			role, ok := chatRole(msg.Author.Role)
+			if !ok {
+				continue
+			}
+			text := chatGPTText(msg)
+			if text == "" {
+				continue
+			}
+			messages = append(messages, models.Message{
+				Role:      role,
+				Content:   text,
+				CreatedAt: unixTime(msg.CreateTime),
+			})
+		}
+
+		convs = append(convs, conversation{
+			sourceID: "chatgpt:" + id,
+			session: models.Session{
+				Title:     item.Title,
+				Model:     item.DefaultModel,
+				CreatedAt: unixTime(item.CreateTime),
+				UpdatedAt: unixTime(item.UpdateTime),
+			},
+			messages: messages,
+		})
+	}
+	return convs, nil
+}
+
+// chatGPTText joins the text parts of a message, skipping images and other
+// non-text parts.
+func chatGPTText(msg *chatGPTMessage) string {
+	var parts []string
+	for _, raw := range msg.Content.Parts {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil && strings.TrimSpace(text) != "" {
+			parts = append(parts, text)
+		}
+	}
+	if len(parts) == 0 && msg.Content.Text != "" {
+		if msg.Content.ContentType == "code" {
+			return "```\n" + msg.Content.Text + "\n```"
+		}
+		return msg.Content.Text
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+type claudeConversation struct {
+	UUID      string          `json:"uuid"`
+	Name      string          `json:"name"`
+	Summary   string          `json:"summary"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Messages  []claudeMessage `json:"chat_messages"`
+}
+
+type claudeMessage struct {
+	UUID      string    `json:"uuid"`
+	Text      string    `json:"text"`
+	Sender    string    `json:"sender"`
+	CreatedAt time.Time `json:"created_at"`
+	Content   []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// parseClaude reads the conversations.json in a Claude data export.
+func parseClaude(data []byte) ([]conversation, error) {
+	var items []claudeConversation
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid Claude export: %w", err)
+	}
+
+	convs := make([]conversation, 0, len(items))
+	for _, item := range items {
+		sort.SliceStable(item.Messages, func(i, j int) bool {
+			return item.Messages[i].CreatedAt.Before(item.Messages[j].CreatedAt)
+		})
+
+		var messages []models.Message
+		for _, msg := range item.Messages {
+			role, ok := chatRole(msg.Sender)
+			if !ok {
+				continue
+			}
+			text := claudeText(msg)
+			if text == "" {
+				continue
+			}
+			messages = append(messages, models.Message{
+				Role:      role,
+				Content:   text,
+				CreatedAt: msg.CreatedAt,
+			})
+		}
+
+		session := models.Session{
+			Title:     item.Name,
+			CreatedAt: item.CreatedAt,
+			UpdatedAt: item.UpdatedAt,
+		}
+		if item.Summary != "" {
+			summary := item.Summary
+			session.Summary = &summary
+		}
+
+		convs = append(convs, conversation{
+			sourceID: "claude:" + item.UUID,
+			session:  session,
+			messages: messages,
+		})
+	}
+	return convs, nil
+}
+
+func claudeText(msg claudeMessage) string {
+	var parts []string
+	for _, c := range msg.Content {
+		if c.Type == "text" && strings.TrimSpace(c.Text) != "" {
+			parts = append(parts, c.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return strings.TrimSpace(msg.Text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// chatRole maps the role names used by other apps onto ours. Tool messages
+// are dropped because they have no matching tool call record.
+func chatRole(role string) (models.MessageRole, bool) {
+	switch role {
+	case "user", "human":
+		return models.RoleUser, true
+	case "assistant":
+		return models.RoleAssistant, true
+	case "system":
+		return models.RoleSystem, true
+	default:
+		return "", false
+	}
+}
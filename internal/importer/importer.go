@@ -0,0 +1,185 @@
+// Package importer reads conversations exported by axe-desktop, ChatGPT and
+// Claude and stores them as sessions.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"axe-desktop/internal/storage"
+	"axe-desktop/pkg/models"
+)
+
+// Result summarises an import.
+type Result struct {
+	Imported []models.Session
+	// Skipped counts conversations that were already imported.
+	Skipped int
+	// Empty counts conversations without any messages.
+	Empty int
+}
+
+// conversation is the common form every source is converted to before it is
+// stored. SourceID identifies the conversation in its source so a re-import
+// can be detected.
+type conversation struct {
+	sourceID string
+	session  models.Session
+	messages []models.Message
+	calls    []models.ToolCall
+}
+
+// Import detects the format of data and stores every conversation in it.
+// name is the file name, used to recognise zip archives. Supported inputs are
+// axe-desktop JSON exports, ChatGPT and Claude conversations.json files, and
+// the zip archives both services export.
+func Import(store *storage.Storage, name string, data []byte) (*Result, error) {
+	if strings.EqualFold(path.Ext(name), ".zip") || bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		var err error
+		data, err = conversationsFromZip(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	convs, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, conv := range convs {
+		if len(conv.messages) == 0 {
+			result.Empty++
+			continue
+		}
+		exists, err := store.SessionSourceExists(conv.sourceID)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.Skipped++
+			continue
+		}
+		session, err := save(store, conv)
+		if err != nil {
+			return result, fmt.Errorf("failed to import %q: %w", conv.session.Title, err)
+		}
+		result.Imported = append(result.Imported, session)
+	}
+	return result, nil
+}
+
+func conversationsFromZip(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if path.Base(f.Name) != "conversations.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("archive does not contain conversations.json")
+}
+
+func parse(data []byte) ([]conversation, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	if data[0] == '{' {
+		return parseExport(data)
+	}
+
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("unrecognised file: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	switch {
+	case items[0]["mapping"] != nil:
+		return parseChatGPT(data)
+	case items[0]["chat_messages"] != nil:
+		return parseClaude(data)
+	default:
+		return nil, fmt.Errorf("unrecognised conversation format")
+	}
+}
+
+// save stores a conversation, removing the partial session if any part fails.
+func save(store *storage.Storage, conv conversation) (models.Session, error) {
+	session := conv.session
+	session.UserID = "default"
+	session.SourceID = conv.sourceID
+	if session.Title == "" {
+		session.Title = "Imported Chat"
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = conv.messages[0].CreatedAt
+	}
+	if session.UpdatedAt.IsZero() {
+		session.UpdatedAt = conv.messages[len(conv.messages)-1].CreatedAt
+	}
+	if err := store.CreateSession(&session); err != nil {
+		return session, err
+	}
+
+	fail := func(err error) (models.Session, error) {
+		store.DeleteSession(session.ID)
+		return session, err
+	}
+
+	// Messages are ordered by time, so make timestamps strictly increasing
+	// for sources that record the same or no time for several messages.
+	var last time.Time
+	for i := range conv.messages {
+		msg := conv.messages[i]
+		msg.SessionID = session.ID
+		if msg.Status == "" {
+			msg.Status = models.StatusCompleted
+		}
+		if msg.CreatedAt.IsZero() {
+			msg.CreatedAt = session.CreatedAt
+		}
+		if !msg.CreatedAt.After(last) && !last.IsZero() {
+			msg.CreatedAt = last.Add(time.Millisecond)
+		}
+		last = msg.CreatedAt
+		if err := store.CreateMessage(&msg); err != nil {
+			return fail(err)
+		}
+	}
+	for i := range conv.calls {
+		tc := conv.calls[i]
+		tc.SessionID = session.ID
+		if err := store.CreateToolCall(&tc); err != nil {
+			return fail(err)
+		}
+	}
+	return session, nil
+}
+
+// unixTime converts the fractional Unix seconds ChatGPT uses.
+func unixTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	sec := int64(seconds)
+	return time.Unix(sec, int64((seconds-float64(sec))*1e9))
+}
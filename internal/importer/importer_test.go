@@ -0,0 +1,228 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"axe-desktop/internal/export"
+	"axe-desktop/internal/storage"
+)
+
+func newTestStorage(t *testing.T) *storage.Storage {
+	t.Helper()
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// exportFixture is an Axe export, shared with the export package's tests.
+var exportFixture = filepath.Join("..", "export", "testdata", "conversation.json")
+
+// readFixture reads a file from testdata, or the export fixture.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if name == exportFixture {
+		path = name
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// transcript returns the role and content of each stored message, oldest
+// first.
+func transcript(t *testing.T, store *storage.Storage, sessionID string) [][2]string {
+	t.Helper()
+	messages, err := store.ListMessages(sessionID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines [][2]string
+	for i := len(messages) - 1; i >= 0; i-- {
+		lines = append(lines, [2]string{string(messages[i].Role), messages[i].Content})
+	}
+	return lines
+}
+
+func TestImportFormats(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		title      string
+		sourceID   string
+		empty      int
+		transcript [][2]string
+	}{
+		{
+			fixture:  "chatgpt.json",
+			title:    "Trip ideas",
+			sourceID: "chatgpt:c1",
+			empty:    1,
+			// Only the branch ending at current_node, without hidden and
+			// tool messages or image parts.
+			transcript: [][2]string{{"user", "Where should I go in May?"}, {"assistant", "Try Lisbon."}},
+		},
+		{
+			fixture:    "claude.json",
+			title:      "Regex help",
+			sourceID:   "claude:d1",
+			transcript: [][2]string{{"user", "How do I match an ISO date?"}, {"assistant", `Use \d{4}-\d{2}-\d{2}.`}},
+		},
+		{
+			fixture:  exportFixture,
+			title:    "Fix the build",
+			sourceID: "2b7f0c1e-0000-4000-8000-000000000001",
+			transcript: [][2]string{
+				{"user", "Why does <script>alert(1)</script> break the build?"},
+				{"assistant", "The test reads a **missing** file."},
+				{"assistant", "Let me check"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.fixture), func(t *testing.T) {
+			store := newTestStorage(t)
+			data := readFixture(t, tt.fixture)
+
+			result, err := Import(store, filepath.Base(tt.fixture), data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Imported) != 1 || result.Empty != tt.empty || result.Skipped != 0 {
+				t.Fatalf("result = %+v", result)
+			}
+			session, err := store.GetSession(result.Imported[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.Title != tt.title {
+				t.Errorf("title = %q, want %q", session.Title, tt.title)
+			}
+			if got := transcript(t, store, session.ID); !reflect.DeepEqual(got, tt.transcript) {
+				t.Errorf("transcript = %q, want %q", got, tt.transcript)
+			}
+
+			// Importing the same file again is recognised through its source.
+			if exists, _ := store.SessionSourceExists(tt.sourceID); !exists {
+				t.Errorf("source %q was not recorded", tt.sourceID)
+			}
+			again, err := Import(store, filepath.Base(tt.fixture), data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(again.Imported) != 0 || again.Skipped != 1 {
+				t.Errorf("second import = %+v, want it skipped", again)
+			}
+		})
+	}
+}
+
+func TestImportZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("export/conversations.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(readFixture(t, "claude.json"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Import(newTestStorage(t), "data.zip", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 1 || result.Imported[0].Title != "Regex help" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// TestExportImportRoundTrip exports a session, imports it into another
+// database and exports it again from there.
+func TestExportImportRoundTrip(t *testing.T) {
+	source := newTestStorage(t)
+	result, err := Import(source, "conversation.json", readFixture(t, exportFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := result.Imported[0].ID
+	if err := source.SetContextSummary(sessionID, "Summary so far.", result.Imported[0].CreatedAt); err != nil {
+		t.Fatal(err)
+	}
+	original, err := export.Load(source, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file bytes.Buffer
+	if err := export.WriteJSON(&file, original); err != nil {
+		t.Fatal(err)
+	}
+
+	target := newTestStorage(t)
+	result, err = Import(target, "chat.json", file.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	imported, err := export.Load(target, result.Imported[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Attachments are not carried over because their files are not part of
+	// the export; everything else is.
+	want := *original
+	want.ExportedAt, imported.ExportedAt = time.Time{}, time.Time{}
+	want.Attachments = nil
+	if !reflect.DeepEqual(imported.Session, want.Session) {
+		t.Errorf("session = %+v\nwant %+v", imported.Session, want.Session)
+	}
+	if !reflect.DeepEqual(imported.Messages, want.Messages) {
+		t.Errorf("messages = %+v\nwant %+v", imported.Messages, want.Messages)
+	}
+	if !reflect.DeepEqual(imported.ToolCalls, want.ToolCalls) {
+		t.Errorf("tool calls = %+v\nwant %+v", imported.ToolCalls, want.ToolCalls)
+	}
+
+	// Importing the file again is skipped in either database.
+	for name, store := range map[string]*storage.Storage{"source": source, "target": target} {
+		again, err := Import(store, "chat.json", file.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Skipped != 1 || len(again.Imported) != 0 {
+			t.Errorf("%s: second import = %+v, want it skipped", name, again)
+		}
+	}
+}
+
+func TestImportRejectsUnknownFiles(t *testing.T) {
+	store := newTestStorage(t)
+	for name, data := range map[string]string{
+		"empty":        "  ",
+		"not json":     "hello",
+		"other json":   `{"name": "x"}`,
+		"newer":        `{"format_version": 99, "session": {"id": "s"}}`,
+		"unknown list": `[{"foo": 1}]`,
+	} {
+		if _, err := Import(store, "file.json", []byte(data)); err == nil {
+			t.Errorf("%s: imported without an error", name)
+		}
+	}
+	if sessions, _ := store.ListSessions("default"); len(sessions) != 0 {
+		t.Errorf("sessions were created: %+v", sessions)
+	}
+}
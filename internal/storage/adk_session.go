@@ -0,0 +1,562 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/adk/session"
+)
+
+const (
+	stateAppPrefix  = "app:"
+	stateUserPrefix = "user:"
+	stateTempPrefix = "temp:"
+)
+
+// adkSessionService is a SQLite-backed implementation of the ADK session.Service.
+// It persists sessions, their events and app/user/session scoped state so that
+// the agent keeps its conversation history across restarts.
+type adkSessionService struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// SessionService returns an ADK session.Service backed by this database.
+func (s *Storage) SessionService() session.Service {
+	return &adkSessionService{db: s.db}
+}
+
+func (s *adkSessionService) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+	if req.AppName == "" || req.UserID == "" {
+		return nil, fmt.Errorf("app_name and user_id are required, got app_name: %q, user_id: %q", req.AppName, req.UserID)
+	}
+
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = uuid.NewString()
+	}
+
+	appDelta, userDelta, sessionState := extractStateDeltas(req.State)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM adk_sessions WHERE app_name = ? AND user_id = ? AND id = ?`,
+		req.AppName, req.UserID, sessionID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, fmt.Errorf("session %s already exists", sessionID)
+	}
+
+	stateJSON, err := json.Marshal(sessionState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session state: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO adk_sessions (app_name, user_id, id, state_json, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		req.AppName, req.UserID, sessionID, stateJSON, now, now,
+	); err != nil {
+		return nil, err
+	}
+
+	appState, err := updateAppState(ctx, tx, req.AppName, appDelta)
+	if err != nil {
+		return nil, err
+	}
+	userState, err := updateUserState(ctx, tx, req.AppName, req.UserID, userDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &session.CreateResponse{
+		Session: &adkSession{
+			appName:   req.AppName,
+			userID:    req.UserID,
+			id:        sessionID,
+			state:     mergeStates(appState, userState, sessionState),
+			updatedAt: now,
+		},
+	}, nil
+}
+
+func (s *adkSessionService) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+
+	sess, err := s.loadSession(ctx, req.AppName, req.UserID, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT event_json FROM adk_events WHERE app_name = ? AND user_id = ? AND session_id = ?`
+	args := []any{req.AppName, req.UserID, req.SessionID}
+	if !req.After.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, req.After)
+	}
+	query += ` ORDER BY seq DESC`
+	if req.NumRecentEvents > 0 {
+		query += fmt.Sprintf(" LIMIT %d", req.NumRecentEvents)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*session.Event
+	for rows.Next() {
+		var eventJSON []byte
+		if err := rows.Scan(&eventJSON); err != nil {
+			return nil, err
+		}
+		var event session.Event
+		if err := json.Unmarshal(eventJSON, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	sess.events = events
+
+	return &session.GetResponse{Session: sess}, nil
+}
+
+func (s *adkSessionService) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
+	if req.AppName == "" {
+		return nil, fmt.Errorf("app_name is required, got app_name: %q", req.AppName)
+	}
+
+	query := `SELECT user_id, id FROM adk_sessions WHERE app_name = ?`
+	args := []any{req.AppName}
+	if req.UserID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, req.UserID)
+	}
+	query += ` ORDER BY updated_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ userID, id string }
+	var keys []key
+	for rows.Next() {
+		var k key
+		if err := rows.Scan(&k.userID, &k.id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]session.Session, 0, len(keys))
+	for _, k := range keys {
+		sess, err := s.loadSession(ctx, req.AppName, k.userID, k.id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	return &session.ListResponse{Sessions: sessions}, nil
+}
+
+func (s *adkSessionService) Delete(ctx context.Context, req *session.DeleteRequest) error {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM adk_events WHERE app_name = ? AND user_id = ? AND session_id = ?`,
+		req.AppName, req.UserID, req.SessionID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM adk_sessions WHERE app_name = ? AND user_id = ? AND id = ?`,
+		req.AppName, req.UserID, req.SessionID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *adkSessionService) AppendEvent(ctx context.Context, curSession session.Session, event *session.Event) error {
+	if curSession == nil {
+		return fmt.Errorf("session is nil")
+	}
+	if event == nil {
+		return fmt.Errorf("event is nil")
+	}
+	if event.Partial {
+		return nil
+	}
+
+	sess, ok := curSession.(*adkSession)
+	if !ok {
+		return fmt.Errorf("unexpected session type %T", curSession)
+	}
+
+	trimTempDeltaState(event)
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stateJSON []byte
+	err = tx.QueryRowContext(ctx,
+		`SELECT state_json FROM adk_sessions WHERE app_name = ? AND user_id = ? AND id = ?`,
+		sess.appName, sess.userID, sess.id,
+	).Scan(&stateJSON)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("session not found, cannot apply event")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO adk_events (id, app_name, user_id, session_id, invocation_id, author, timestamp, event_json)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.ID, sess.appName, sess.userID, sess.id, event.InvocationID, event.Author, event.Timestamp, eventJSON,
+	); err != nil {
+		return err
+	}
+
+	storedState := make(map[string]any)
+	if len(stateJSON) > 0 {
+		if err := json.Unmarshal(stateJSON, &storedState); err != nil {
+			return fmt.Errorf("failed to decode session state: %w", err)
+		}
+	}
+
+	if len(event.Actions.StateDelta) > 0 {
+		appDelta, userDelta, sessionDelta := extractStateDeltas(event.Actions.StateDelta)
+		if _, err := updateAppState(ctx, tx, sess.appName, appDelta); err != nil {
+			return err
+		}
+		if _, err := updateUserState(ctx, tx, sess.appName, sess.userID, userDelta); err != nil {
+			return err
+		}
+		maps.Copy(storedState, sessionDelta)
+		if stateJSON, err = json.Marshal(storedState); err != nil {
+			return fmt.Errorf("failed to encode session state: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE adk_sessions SET state_json = ?, updated_at = ? WHERE app_name = ? AND user_id = ? AND id = ?`,
+		stateJSON, event.Timestamp, sess.appName, sess.userID, sess.id,
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sess.appendEvent(event)
+	return nil
+}
+
+func (s *adkSessionService) loadSession(ctx context.Context, appName, userID, sessionID string) (*adkSession, error) {
+	var stateJSON []byte
+	var updatedAt time.Time
+	err := s.db.QueryRowContext(ctx,
+		`SELECT state_json, updated_at FROM adk_sessions WHERE app_name = ? AND user_id = ? AND id = ?`,
+		appName, userID, sessionID,
+	).Scan(&stateJSON, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sessionState := make(map[string]any)
+	if len(stateJSON) > 0 {
+		if err := json.Unmarshal(stateJSON, &sessionState); err != nil {
+			return nil, fmt.Errorf("failed to decode session state: %w", err)
+		}
+	}
+
+	appState, err := loadState(ctx, s.db, `SELECT state_json FROM adk_app_states WHERE app_name = ?`, appName)
+	if err != nil {
+		return nil, err
+	}
+	userState, err := loadState(ctx, s.db, `SELECT state_json FROM adk_user_states WHERE app_name = ? AND user_id = ?`, appName, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &adkSession{
+		appName:   appName,
+		userID:    userID,
+		id:        sessionID,
+		state:     mergeStates(appState, userState, sessionState),
+		updatedAt: updatedAt,
+	}, nil
+}
+
+type execQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func loadState(ctx context.Context, q execQuerier, query string, args ...any) (map[string]any, error) {
+	state := make(map[string]any)
+	var stateJSON []byte
+	err := q.QueryRowContext(ctx, query, args...).Scan(&stateJSON)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(stateJSON) > 0 {
+		if err := json.Unmarshal(stateJSON, &state); err != nil {
+			return nil, fmt.Errorf("failed to decode state: %w", err)
+		}
+	}
+	return state, nil
+}
+
+func updateAppState(ctx context.Context, q execQuerier, appName string, delta map[string]any) (map[string]any, error) {
+	state, err := loadState(ctx, q, `SELECT state_json FROM adk_app_states WHERE app_name = ?`, appName)
+	if err != nil || len(delta) == 0 {
+		return state, err
+	}
+	maps.Copy(state, delta)
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode app state: %w", err)
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO adk_app_states (app_name, state_json, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT(app_name) DO UPDATE SET state_json = excluded.state_json, updated_at = excluded.updated_at`,
+		appName, stateJSON, time.Now(),
+	)
+	return state, err
+}
+
+func updateUserState(ctx context.Context, q execQuerier, appName, userID string, delta map[string]any) (map[string]any, error) {
+	state, err := loadState(ctx, q, `SELECT state_json FROM adk_user_states WHERE app_name = ? AND user_id = ?`, appName, userID)
+	if err != nil || len(delta) == 0 {
+		return state, err
+	}
+	maps.Copy(state, delta)
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user state: %w", err)
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO adk_user_states (app_name, user_id, state_json, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(app_name, user_id) DO UPDATE SET state_json = excluded.state_json, updated_at = excluded.updated_at`,
+		appName, userID, stateJSON, time.Now(),
+	)
+	return state, err
+}
+
+func extractStateDeltas(delta map[string]any) (appDelta, userDelta, sessionDelta map[string]any) {
+	appDelta = make(map[string]any)
+	userDelta = make(map[string]any)
+	sessionDelta = make(map[string]any)
+	for key, value := range delta {
+		if clean, ok := strings.CutPrefix(key, stateAppPrefix); ok {
+			appDelta[clean] = value
+		} else if clean, ok := strings.CutPrefix(key, stateUserPrefix); ok {
+			userDelta[clean] = value
+		} else if !strings.HasPrefix(key, stateTempPrefix) {
+			sessionDelta[key] = value
+		}
+	}
+	return appDelta, userDelta, sessionDelta
+}
+
+func mergeStates(appState, userState, sessionState map[string]any) map[string]any {
+	merged := make(map[string]any, len(appState)+len(userState)+len(sessionState))
+	maps.Copy(merged, sessionState)
+	for key, value := range appState {
+		merged[stateAppPrefix+key] = value
+	}
+	for key, value := range userState {
+		merged[stateUserPrefix+key] = value
+	}
+	return merged
+}
+
+func trimTempDeltaState(event *session.Event) {
+	if len(event.Actions.StateDelta) == 0 {
+		return
+	}
+	filtered := make(map[string]any, len(event.Actions.StateDelta))
+	for key, value := range event.Actions.StateDelta {
+		if !strings.HasPrefix(key, stateTempPrefix) {
+			filtered[key] = value
+		}
+	}
+	event.Actions.StateDelta = filtered
+}
+
+type adkSession struct {
+	appName string
+	userID  string
+	id      string
+
+	mu        sync.RWMutex
+	events    []*session.Event
+	state     map[string]any
+	updatedAt time.Time
+}
+
+func (s *adkSession) ID() string      { return s.id }
+func (s *adkSession) AppName() string { return s.appName }
+func (s *adkSession) UserID() string  { return s.userID }
+
+func (s *adkSession) State() session.State {
+	return &adkState{mu: &s.mu, state: s.state}
+}
+
+func (s *adkSession) Events() session.Events {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return adkEvents(s.events)
+}
+
+func (s *adkSession) LastUpdateTime() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+func (s *adkSession) appendEvent(event *session.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, value := range event.Actions.StateDelta {
+		if !strings.HasPrefix(key, stateTempPrefix) {
+			s.state[key] = value
+		}
+	}
+	s.events = append(s.events, event)
+	s.updatedAt = event.Timestamp
+}
+
+type adkEvents []*session.Event
+
+func (e adkEvents) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for _, event := range e {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+func (e adkEvents) Len() int {
+	return len(e)
+}
+
+func (e adkEvents) At(i int) *session.Event {
+	if i >= 0 && i < len(e) {
+		return e[i]
+	}
+	return nil
+}
+
+type adkState struct {
+	mu    *sync.RWMutex
+	state map[string]any
+}
+
+func (s *adkState) Get(key string) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	val, ok := s.state[key]
+	if !ok {
+		return nil, session.ErrStateKeyNotExist
+	}
+	return val, nil
+}
+
+func (s *adkState) Set(key string, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state[key] = value
+	return nil
+}
+
+func (s *adkState) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		s.mu.RLock()
+		snapshot := maps.Clone(s.state)
+		s.mu.RUnlock()
+
+		for k, v := range snapshot {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+var _ session.Service = (*adkSessionService)(nil)
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+const testApp = "axe"
+
+func createADKSession(t *testing.T, svc session.Service, id string, state map[string]any) session.Session {
+	t.Helper()
+	resp, err := svc.Create(context.Background(), &session.CreateRequest{
+		AppName: testApp, UserID: "default", SessionID: id, State: state,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Session
+}
+
+func getADKSession(t *testing.T, svc session.Service, req *session.GetRequest) session.Session {
+	t.Helper()
+	req.AppName, req.UserID = testApp, "default"
+	resp, err := svc.Get(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Session
+}
+
+// textEvent returns an event with one text part, written at the given
+// minute past a fixed time.
+func textEvent(text string, minute int) *session.Event {
+	event := session.NewEvent("inv")
+	event.Author = "user"
+	event.Timestamp = time.Date(2026, 1, 2, 3, minute, 0, 0, time.UTC)
+	event.Content = genai.NewContentFromText(text, genai.RoleUser)
+	return event
+}
+
+func eventTexts(sess session.Session) []string {
+	var texts []string
+	for event := range sess.Events().All() {
+		texts = append(texts, event.Content.Parts[0].Text)
+	}
+	return texts
+}
+
+func TestADKSessionPersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := createADKSession(t, s.SessionService(), "s1", nil)
+
+	call := session.NewEvent("inv")
+	call.Author = "agent"
+	call.Content = &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+		{Text: "Listing files."},
+		{FunctionCall: &genai.FunctionCall{ID: "c1", Name: "list_files", Args: map[string]any{"path": "."}}},
+	}}
+	result := session.NewEvent("inv")
+	result.Author = "agent"
+	result.Content = &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{
+		{FunctionResponse: &genai.FunctionResponse{ID: "c1", Name: "list_files", Response: map[string]any{"files": []any{"main.go"}}}},
+	}}
+	for _, event := range []*session.Event{call, result} {
+		if err := s.SessionService().AppendEvent(ctx, sess, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sess.Events().Len() != 2 {
+		t.Errorf("in-memory session has %d events, want 2", sess.Events().Len())
+	}
+	s.Close()
+
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	got := getADKSession(t, s.SessionService(), &session.GetRequest{SessionID: "s1"})
+	if got.Events().Len() != 2 {
+		t.Fatalf("reopened session has %d events, want 2", got.Events().Len())
+	}
+
+	parts := got.Events().At(0).Content.Parts
+	if len(parts) != 2 || parts[0].Text != "Listing files." || parts[1].FunctionCall == nil {
+		t.Fatalf("call parts = %+v", parts)
+	}
+	if fc := parts[1].FunctionCall; fc.ID != "c1" || fc.Name != "list_files" || fc.Args["path"] != "." {
+		t.Errorf("function call = %+v", fc)
+	}
+	fr := got.Events().At(1).Content.Parts[0].FunctionResponse
+	if fr == nil || fr.ID != "c1" || fr.Name != "list_files" {
+		t.Fatalf("function response = %+v", fr)
+	}
+	if files, _ := fr.Response["files"].([]any); len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("function response = %+v", fr.Response)
+	}
+	if got.Events().At(0).ID != call.ID || got.Events().At(0).Author != "agent" {
+		t.Errorf("event = %+v, want id %s by agent", got.Events().At(0), call.ID)
+	}
+}
+
+func TestADKSessionGetFilters(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestStorage(t).SessionService()
+	sess := createADKSession(t, svc, "s1", nil)
+	for i, text := range []string{"one", "two", "three", "four"} {
+		if err := svc.AppendEvent(ctx, sess, textEvent(text, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  *session.GetRequest
+		want []string
+	}{
+		{"all", &session.GetRequest{SessionID: "s1"}, []string{"one", "two", "three", "four"}},
+		{"recent", &session.GetRequest{SessionID: "s1", NumRecentEvents: 2}, []string{"three", "four"}},
+		{"after", &session.GetRequest{SessionID: "s1", After: textEvent("", 1).Timestamp}, []string{"two", "three", "four"}},
+		{"after and recent", &session.GetRequest{SessionID: "s1", After: textEvent("", 1).Timestamp, NumRecentEvents: 1}, []string{"four"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := eventTexts(getADKSession(t, svc, tt.req))
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("events = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestADKSessionSkipsPartialEvents(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestStorage(t).SessionService()
+	sess := createADKSession(t, svc, "s1", nil)
+
+	partial := textEvent("Hel", 0)
+	partial.Partial = true
+	if err := svc.AppendEvent(ctx, sess, partial); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AppendEvent(ctx, sess, textEvent("Hello", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := eventTexts(getADKSession(t, svc, &session.GetRequest{SessionID: "s1"}))
+	if len(got) != 1 || got[0] != "Hello" {
+		t.Errorf("events = %q, want only the complete event", got)
+	}
+	if sess.Events().Len() != 1 {
+		t.Errorf("in-memory session has %d events, want 1", sess.Events().Len())
+	}
+}
+
+func TestADKSessionState(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestStorage(t).SessionService()
+	sess := createADKSession(t, svc, "s1", map[string]any{
+		"app:theme": "dark",
+		"user:name": "Ada",
+		"step":      "start",
+		"temp:seen": true,
+	})
+
+	event := textEvent("next", 0)
+	event.Actions.StateDelta = map[string]any{
+		"app:theme":  "light",
+		"user:lang":  "en",
+		"step":       "next",
+		"temp:draft": "x",
+	}
+	if err := svc.AppendEvent(ctx, sess, event); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := event.Actions.StateDelta["temp:draft"]; ok {
+		t.Error("temp: delta was stored with the event")
+	}
+
+	// A second session of the same user sees app and user state but not the
+	// first session's own state.
+	createADKSession(t, svc, "s2", nil)
+
+	tests := []struct {
+		session string
+		want    map[string]any
+	}{
+		{"s1", map[string]any{"app:theme": "light", "user:name": "Ada", "user:lang": "en", "step": "next"}},
+		{"s2", map[string]any{"app:theme": "light", "user:name": "Ada", "user:lang": "en"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.session, func(t *testing.T) {
+			got := map[string]any{}
+			for k, v := range getADKSession(t, svc, &session.GetRequest{SessionID: tt.session}).State().All() {
+				got[k] = v
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("state = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("state[%q] = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestADKSessionListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestStorage(t).SessionService()
+	sess := createADKSession(t, svc, "s1", nil)
+	createADKSession(t, svc, "s2", nil)
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: testApp, UserID: "other", SessionID: "s3"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, &session.CreateRequest{AppName: testApp, UserID: "default", SessionID: "s1"}); err == nil {
+		t.Error("creating an existing session succeeded")
+	}
+	if err := svc.AppendEvent(ctx, sess, textEvent("hi", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(userID string) map[string]bool {
+		t.Helper()
+		resp, err := svc.List(ctx, &session.ListRequest{AppName: testApp, UserID: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := map[string]bool{}
+		for _, s := range resp.Sessions {
+			ids[s.ID()] = true
+		}
+		return ids
+	}
+	if ids := list("default"); len(ids) != 2 || !ids["s1"] || !ids["s2"] {
+		t.Errorf("default sessions = %v, want s1 and s2", ids)
+	}
+	if ids := list(""); len(ids) != 3 {
+		t.Errorf("all sessions = %v, want 3", ids)
+	}
+
+	if err := svc.Delete(ctx, &session.DeleteRequest{AppName: testApp, UserID: "default", SessionID: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := list("default"); len(ids) != 1 || !ids["s2"] {
+		t.Errorf("default sessions after delete = %v, want s2", ids)
+	}
+	if _, err := svc.Get(ctx, &session.GetRequest{AppName: testApp, UserID: "default", SessionID: "s1"}); err == nil {
+		t.Error("deleted session can still be loaded")
+	}
+	if err := svc.AppendEvent(ctx, sess, textEvent("again", 1)); err == nil {
+		t.Error("appending to a deleted session succeeded")
+	}
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is a numbered schema change. Migrations run in order, each in its
+// own transaction, and are recorded in schema_version once applied.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations must only ever be appended to; released versions are immutable.
+var migrations = []migration{
+	{1, "initial schema", execAll(
+		`
+		CREATE TABLE IF NOT EXISTS users (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id),
+			title TEXT NOT NULL,
+			model TEXT NOT NULL,
+			system_prompt TEXT,
+			summary TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			archived_at DATETIME,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS messages (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL REFERENCES sessions(id),
+			role TEXT NOT NULL,
+			content TEXT NOT NULL,
+			status TEXT DEFAULT 'completed',
+			token_count INTEGER,
+			metadata_json TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS tool_calls (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL REFERENCES sessions(id),
+			message_id TEXT NOT NULL REFERENCES messages(id),
+			tool_name TEXT NOT NULL,
+			args_json TEXT,
+			result_json TEXT,
+			error TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS attachments (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL REFERENCES sessions(id),
+			message_id TEXT REFERENCES messages(id),
+			type TEXT NOT NULL,
+			path TEXT NOT NULL,
+			metadata_json TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value_json TEXT
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_session_created ON messages(session_id, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_tool_calls_session_created ON tool_calls(session_id, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user_updated ON sessions(user_id, updated_at);`,
+		`INSERT OR IGNORE INTO users (id, name, created_at) VALUES ('default', 'Default User', CURRENT_TIMESTAMP);`,
+	)},
+	{2, "adk session tables", execAll(
+		`
+		CREATE TABLE IF NOT EXISTS adk_sessions (
+			app_name TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			id TEXT NOT NULL,
+			state_json TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (app_name, user_id, id)
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS adk_events (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			id TEXT NOT NULL UNIQUE,
+			app_name TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			session_id TEXT NOT NULL,
+			invocation_id TEXT,
+			author TEXT,
+			timestamp DATETIME,
+			event_json TEXT NOT NULL
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS adk_app_states (
+			app_name TEXT PRIMARY KEY,
+			state_json TEXT,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS adk_user_states (
+			app_name TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			state_json TEXT,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (app_name, user_id)
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS idx_adk_events_session ON adk_events(app_name, user_id, session_id, seq);`,
+	)},
+	{3, "sessions.provider_id", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "sessions", "provider_id", "TEXT NOT NULL DEFAULT ''")
+	}},
+	{4, "tool_calls.duration_ms", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "tool_calls", "duration_ms", "INTEGER")
+	}},
+	// Full-text search needs SQLite built with FTS5 (the sqlite_fts5 tag).
+	// Without it the version is still recorded and search falls back to
+	// LIKE; prepareSearch creates the index once FTS5 is available.
+	{5, "full-text search", func(tx *sql.Tx) error {
+		if !fts5Available(tx) {
+			return nil
+		}
+		return execAll(searchIndex...)(tx)
+	}},
+	// source_id identifies where an imported session came from, so importing
+	// the same file twice does not duplicate it.
+	{6, "sessions.source_id", func(tx *sql.Tx) error {
+		if err := addColumnIfMissing(tx, "sessions", "source_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_source ON sessions(source_id) WHERE source_id != ''`)
+		return err
+	}},
+	// context_summary stands in for the messages up to summary_until, which
+	// are no longer sent to the model. It is kept apart from summary, the
+	// one-line description shown in the sidebar.
+	{7, "sessions.context_summary", func(tx *sql.Tx) error {
+		if err := addColumnIfMissing(tx, "sessions", "context_summary", "TEXT"); err != nil {
+			return err
+		}
+		return addColumnIfMissing(tx, "sessions", "summary_until", "DATETIME")
+	}},
+	// workspace is the folder a session's file tools work in.
+	{8, "sessions.workspace", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "sessions", "workspace", "TEXT NOT NULL DEFAULT ''")
+	}},
+}
+
+// schemaVersion is the version this build of the app writes.
+func schemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func (s *Storage) migrate() error {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_version: %w", err)
+	}
+
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	latest := schemaVersion()
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this version of Axe Desktop supports (%d); please upgrade", current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	if err := s.backup(current); err != nil {
+		return fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := s.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) applyMigration(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backup copies the database next to the original before it is migrated.
+// Fresh databases without any tables are not backed up.
+func (s *Storage) backup(version int) error {
+	if s.path == "" || s.path == ":memory:" {
+		return nil
+	}
+
+	var tables int
+	if err := s.db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_version', 'sqlite_sequence')`,
+	).Scan(&tables); err != nil {
+		return err
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", s.path, version, time.Now().Format("20060102-150405"))
+	_, err := s.db.Exec(`VACUUM INTO ?`, backupPath)
+	return err
+}
+
+func execAll(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumnIfMissing adds a column unless it already exists, which is the
+// case for databases that were upgraded before schema versions were tracked.
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func storedVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fresh.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if got := storedVersion(t, s.db); got != schemaVersion() {
+		t.Errorf("schema version = %d, want %d", got, schemaVersion())
+	}
+	if _, err := s.db.Exec(`SELECT provider_id, source_id, context_summary, summary_until, workspace FROM sessions`); err != nil {
+		t.Errorf("sessions is missing columns: %v", err)
+	}
+	if backups, _ := filepath.Glob(path + ".v*.bak"); len(backups) != 0 {
+		t.Errorf("fresh database was backed up: %v", backups)
+	}
+}
+
+// TestMigrateBaselineDatabase upgrades a database written before schema
+// versions were tracked, which already has one of the later columns.
+func TestMigrateBaselineDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations[0].up(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`ALTER TABLE sessions ADD COLUMN provider_id TEXT NOT NULL DEFAULT ''`,
+		`INSERT INTO sessions (id, user_id, title, model, system_prompt, summary, provider_id) VALUES ('s1', 'default', 'Kept', 'm', '', '', 'p1')`,
+		`INSERT INTO messages (id, session_id, role, content) VALUES ('m1', 's1', 'user', 'hello')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if got := storedVersion(t, s.db); got != schemaVersion() {
+		t.Errorf("schema version = %d, want %d", got, schemaVersion())
+	}
+	session, err := s.GetSession("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Title != "Kept" || session.ProviderID != "p1" || session.Workspace != "" {
+		t.Errorf("session = %+v", session)
+	}
+
+	backups, _ := filepath.Glob(path + ".v0-*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one of version 0", backups)
+	}
+	backup, err := sql.Open("sqlite3", backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var messages int
+	if err := backup.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&messages); err != nil || messages != 1 {
+		t.Errorf("backup has %d messages (%v), want 1", messages, err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "newer.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec(`INSERT INTO schema_version (version, name) VALUES (?, 'from the future')`, schemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = New(path)
+	if err == nil {
+		s.Close()
+		t.Fatal("opened a database with a newer schema")
+	}
+	if !strings.Contains(err.Error(), "newer than this version") {
+		t.Errorf("error = %v", err)
+	}
+}
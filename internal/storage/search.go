@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"axe-desktop/pkg/models"
+)
+
+// Snippet markers surround the matched terms in SearchResult.Snippet. They
+// are control characters so they cannot be confused with message text.
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+// searchIndex creates the FTS5 indexes. They use the source tables as
+// external content, keyed by rowid, and are kept in sync by triggers.
+var searchIndex = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(content, content='messages', content_rowid='rowid');`,
+	`
+	CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
+	END;
+	`,
+	`
+	CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+	END;
+	`,
+	`
+	CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+		INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
+	END;
+	`,
+	`
+	CREATE VIRTUAL TABLE IF NOT EXISTS tool_calls_fts USING fts5(
+		tool_name, args_json, result_json,
+		content='tool_calls', content_rowid='rowid'
+	);
+	`,
+	`
+	CREATE TRIGGER IF NOT EXISTS tool_calls_fts_insert AFTER INSERT ON tool_calls BEGIN
+		INSERT INTO tool_calls_fts(rowid, tool_name, args_json, result_json)
+		VALUES (new.rowid, new.tool_name, new.args_json, new.result_json);
+	END;
+	`,
+	`
+	CREATE TRIGGER IF NOT EXISTS tool_calls_fts_delete AFTER DELETE ON tool_calls BEGIN
+		INSERT INTO tool_calls_fts(tool_calls_fts, rowid, tool_name, args_json, result_json)
+		VALUES ('delete', old.rowid, old.tool_name, old.args_json, old.result_json);
+	END;
+	`,
+	`
+	CREATE TRIGGER IF NOT EXISTS tool_calls_fts_update AFTER UPDATE ON tool_calls BEGIN
+		INSERT INTO tool_calls_fts(tool_calls_fts, rowid, tool_name, args_json, result_json)
+		VALUES ('delete', old.rowid, old.tool_name, old.args_json, old.result_json);
+		INSERT INTO tool_calls_fts(rowid, tool_name, args_json, result_json)
+		VALUES (new.rowid, new.tool_name, new.args_json, new.result_json);
+	END;
+	`,
+	`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild');`,
+	`INSERT INTO tool_calls_fts(tool_calls_fts) VALUES ('rebuild');`,
+}
+
+// searchTriggers are the triggers created by searchIndex.
+var searchTriggers = []string{
+	"messages_fts_insert", "messages_fts_delete", "messages_fts_update",
+	"tool_calls_fts_insert", "tool_calls_fts_delete", "tool_calls_fts_update",
+}
+
+// fts5Available reports whether SQLite was built with FTS5.
+func fts5Available(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}) bool {
+	var used bool
+	err := q.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&used)
+	return err == nil && used
+}
+
+// prepareSearch decides between full-text and LIKE search. With FTS5 it
+// creates the index if an earlier build without FTS5 skipped it. Without
+// FTS5 it drops the index triggers, which would otherwise make every write
+// to messages and tool_calls fail; the index is rebuilt once FTS5 is back.
+func (s *Storage) prepareSearch() error {
+	s.fts = fts5Available(s.db)
+	if !s.fts {
+		for _, name := range searchTriggers {
+			if _, err := s.db.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var triggers int
+	if err := s.db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?, ?, ?, ?, ?)`,
+		searchTriggers[0], searchTriggers[1], searchTriggers[2], searchTriggers[3], searchTriggers[4], searchTriggers[5],
+	).Scan(&triggers); err != nil {
+		return err
+	}
+	if triggers == len(searchTriggers) {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := execAll(searchIndex...)(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SearchMessages searches message content and tool call arguments and
+// results in active sessions. With FTS5, bm25 scores from the two indexes
+// are not comparable, so each is ranked on its own and the results are
+// interleaved: best message, best tool call, second message and so on.
+// Without FTS5 every word must appear as a substring and the newest
+// matches come first.
+func (s *Storage) SearchMessages(query string, limit int) ([]models.SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if !s.fts {
+		return s.searchLike(strings.Fields(query), limit)
+	}
+
+	match := ftsQuery(query)
+	rows, err := s.db.Query(
+		`SELECT message_id, session_id, title, role, snippet, rank, created_at FROM (
+			SELECT *, 0 AS source, ROW_NUMBER() OVER (ORDER BY rank) AS pos FROM (
+				SELECT m.id AS message_id, m.session_id AS session_id, s.title AS title, m.role AS role,
+					snippet(messages_fts, 0, ?, ?, '…', 12) AS snippet, bm25(messages_fts) AS rank, m.created_at AS created_at
+				FROM messages_fts
+				JOIN messages m ON m.rowid = messages_fts.rowid
+				JOIN sessions s ON s.id = m.session_id
+				WHERE messages_fts MATCH ? AND s.archived_at IS NULL
+			)
+			UNION ALL
+			SELECT *, 1 AS source, ROW_NUMBER() OVER (ORDER BY rank) AS pos FROM (
+				SELECT tc.message_id AS message_id, tc.session_id AS session_id, s.title AS title, 'tool' AS role,
+					tc.tool_name || ': ' || snippet(tool_calls_fts, -1, ?, ?, '…', 12) AS snippet, bm25(tool_calls_fts) AS rank, tc.created_at AS created_at
+				FROM tool_calls_fts
+				JOIN tool_calls tc ON tc.rowid = tool_calls_fts.rowid
+				JOIN sessions s ON s.id = tc.session_id
+				WHERE tool_calls_fts MATCH ? AND s.archived_at IS NULL
+			)
+		)
+		ORDER BY pos, source
+		LIMIT ?`,
+		SnippetStart, SnippetEnd, match,
+		SnippetStart, SnippetEnd, match,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		var r models.SearchResult
+		if err := rows.Scan(&r.MessageID, &r.SessionID, &r.SessionTitle, &r.Role, &r.Snippet, &r.Rank, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.Snippet = strings.Join(strings.Fields(r.Snippet), " ")
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// searchLike is the search used when SQLite lacks FTS5.
+func (s *Storage) searchLike(words []string, limit int) ([]models.SearchResult, error) {
+	const toolText = `tc.tool_name || ': ' || COALESCE(tc.args_json, '') || ' ' || COALESCE(tc.result_json, '')`
+	var messageWhere, toolWhere []string
+	var messageArgs, toolArgs []any
+	for _, word := range words {
+		messageWhere = append(messageWhere, `m.content LIKE ? ESCAPE '\'`)
+		toolWhere = append(toolWhere, toolText+` LIKE ? ESCAPE '\'`)
+		messageArgs = append(messageArgs, likePattern(word))
+		toolArgs = append(toolArgs, likePattern(word))
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(
+		`SELECT m.id, m.session_id, s.title, m.role, m.content, m.created_at
+		 FROM messages m
+		 JOIN sessions s ON s.id = m.session_id
+		 WHERE s.archived_at IS NULL AND %s
+		 UNION ALL
+		 SELECT tc.message_id, tc.session_id, s.title, 'tool', %s, tc.created_at
+		 FROM tool_calls tc
+		 JOIN sessions s ON s.id = tc.session_id
+		 WHERE s.archived_at IS NULL AND %s
+		 ORDER BY 6 DESC
+		 LIMIT ?`,
+		strings.Join(messageWhere, " AND "), toolText, strings.Join(toolWhere, " AND "),
+	), append(append(messageArgs, toolArgs...), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		var r models.SearchResult
+		var text string
+		if err := rows.Scan(&r.MessageID, &r.SessionID, &r.SessionTitle, &r.Role, &text, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.Snippet = likeSnippet(text, words)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// ftsQuery turns free text into an FTS5 query that matches every word as a
+// prefix, quoting each word so operators and punctuation are taken literally.
+func ftsQuery(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		word = strings.ReplaceAll(word, `"`, `""`)
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// likePattern matches word anywhere in a value, escaping LIKE wildcards.
+func likePattern(word string) string {
+	word = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(word)
+	return "%" + word + "%"
+}
+
+// Bytes of text kept before and after the first match in a LIKE snippet.
+const (
+	snippetBefore = 40
+	snippetAfter  = 80
+)
+
+// likeSnippet cuts text around the first match and marks every match in the
+// cut, much like FTS5's snippet function.
+func likeSnippet(text string, words []string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	first := 0
+	for i := range text {
+		if matchAt(text, i, words) > 0 {
+			first = i
+			break
+		}
+	}
+	start := max(0, first-snippetBefore)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	end := min(len(text), first+snippetAfter)
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		if n := matchAt(text[:end], i, words); n > 0 {
+			b.WriteString(SnippetStart + text[i:i+n] + SnippetEnd)
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:end])
+		b.WriteString(text[i : i+size])
+		i += size
+	}
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// matchAt returns the length of the longest word found at text[i:], ignoring
+// case, or 0 if none is.
+func matchAt(text string, i int, words []string) int {
+	n := 0
+	for _, word := range words {
+		if len(word) > n && i+len(word) <= len(text) && strings.EqualFold(text[i:i+len(word)], word) {
+			n = len(word)
+		}
+	}
+	return n
+}
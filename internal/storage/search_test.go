@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"axe-desktop/pkg/models"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	s, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// seedSearch creates an active and an archived session with messages and a
+// tool call mentioning "deploy".
+func seedSearch(t *testing.T, s *Storage) (active, archived *models.Session) {
+	t.Helper()
+	active = &models.Session{UserID: "default", Title: "Release", Model: "m"}
+	archived = &models.Session{UserID: "default", Title: "Old", Model: "m"}
+	for _, session := range []*models.Session{active, archived} {
+		if err := s.CreateSession(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	messages := []*models.Message{
+		{SessionID: active.ID, Role: models.RoleUser, Content: "How do I deploy the server?", Status: models.StatusCompleted},
+		{SessionID: active.ID, Role: models.RoleAssistant, Content: "Run the release script.", Status: models.StatusCompleted},
+		{SessionID: archived.ID, Role: models.RoleUser, Content: "deploy notes", Status: models.StatusCompleted},
+	}
+	for _, msg := range messages {
+		if err := s.CreateMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CreateToolCall(&models.ToolCall{
+		SessionID: active.ID, MessageID: messages[1].ID, ToolName: "run_command",
+		Args: map[string]any{"command": "make deploy"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ArchiveSession(archived.ID); err != nil {
+		t.Fatal(err)
+	}
+	return active, archived
+}
+
+func TestSearchMessages(t *testing.T) {
+	for _, fts := range []bool{true, false} {
+		name := "like"
+		if fts {
+			name = "fts5"
+		}
+		t.Run(name, func(t *testing.T) {
+			s := newTestStorage(t)
+			if fts && !s.fts {
+				t.Skip("SQLite was built without FTS5")
+			}
+			s.fts = fts
+			active, _ := seedSearch(t, s)
+
+			results, err := s.SearchMessages("deploy", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roles := map[models.MessageRole]bool{}
+			for _, r := range results {
+				if r.SessionID != active.ID || r.SessionTitle != "Release" {
+					t.Errorf("result from wrong session: %+v", r)
+				}
+				if !strings.Contains(r.Snippet, SnippetStart+"deploy") || !strings.Contains(r.Snippet, SnippetEnd) {
+					t.Errorf("snippet %q does not mark the match", r.Snippet)
+				}
+				roles[r.Role] = true
+			}
+			if len(results) != 2 || !roles[models.RoleUser] || !roles["tool"] {
+				t.Fatalf("results = %+v, want the user message and the tool call", results)
+			}
+
+			results, err = s.SearchMessages("release script", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].Role != models.RoleAssistant {
+				t.Errorf("results = %+v, want the assistant message", results)
+			}
+
+			results, err = s.SearchMessages(`50% "off"`, 10)
+			if err != nil || len(results) != 0 {
+				t.Errorf("punctuation query = %+v, %v", results, err)
+			}
+			if results, _ := s.SearchMessages("  ", 10); results != nil {
+				t.Errorf("blank query returned %+v", results)
+			}
+		})
+	}
+}
+
+func TestSearchIndexFollowsFTS5(t *testing.T) {
+	s := newTestStorage(t)
+	if !s.fts {
+		t.Skip("SQLite was built without FTS5")
+	}
+	active, _ := seedSearch(t, s)
+
+	// A build without FTS5 drops the triggers; the next build with FTS5 must
+	// put them back and index what was written in between.
+	for _, name := range searchTriggers {
+		if _, err := s.db.Exec(`DROP TRIGGER ` + name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CreateMessage(&models.Message{SessionID: active.ID, Role: models.RoleUser, Content: "rollback plan"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.prepareSearch(); err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.SearchMessages("rollback", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("results = %+v, want the message written without the index", results)
+	}
+}
+
+func TestLikeSnippet(t *testing.T) {
+	text := strings.Repeat("filler ", 20) + "the Deploy step " + strings.Repeat("more ", 30)
+	got := likeSnippet(text, []string{"deploy"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet %q is not cut on both sides", got)
+	}
+	if !strings.Contains(got, "the "+SnippetStart+"Deploy"+SnippetEnd+" step") {
+		t.Errorf("snippet %q does not mark the match", got)
+	}
+}
@@ -11,7 +11,10 @@ import (
 )
 
 type Storage struct {
-	db *sql.DB
+	db   *sql.DB
+	path string
+	// fts is set when SQLite has FTS5 and the search index is in place.
+	fts bool
 }
 
 func New(dbPath string) (*Storage, error) {
@@ -24,11 +27,15 @@ func New(dbPath string) (*Storage, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(time.Hour)
 
-	store := &Storage{db: db}
+	store := &Storage{db: db, path: dbPath}
 	if err := store.migrate(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
+	if err := store.prepareSearch(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to prepare search: %w", err)
+	}
 
 	return store, nil
 }
@@ -37,103 +44,23 @@ func (s *Storage) Close() error {
 	return s.db.Close()
 }
 
-func (s *Storage) migrate() error {
-	migrations := []string{
-		`
-		CREATE TABLE IF NOT EXISTS users (
-			id TEXT PRIMARY KEY,
-			name TEXT NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS sessions (
-			id TEXT PRIMARY KEY,
-			user_id TEXT NOT NULL REFERENCES users(id),
-			title TEXT NOT NULL,
-			model TEXT NOT NULL,
-			system_prompt TEXT,
-			summary TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			archived_at DATETIME,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS messages (
-			id TEXT PRIMARY KEY,
-			session_id TEXT NOT NULL REFERENCES sessions(id),
-			role TEXT NOT NULL,
-			content TEXT NOT NULL,
-			status TEXT DEFAULT 'completed',
-			token_count INTEGER,
-			metadata_json TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS tool_calls (
-			id TEXT PRIMARY KEY,
-			session_id TEXT NOT NULL REFERENCES sessions(id),
-			message_id TEXT NOT NULL REFERENCES messages(id),
-			tool_name TEXT NOT NULL,
-			args_json TEXT,
-			result_json TEXT,
-			error TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
-			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS attachments (
-			id TEXT PRIMARY KEY,
-			session_id TEXT NOT NULL REFERENCES sessions(id),
-			message_id TEXT REFERENCES messages(id),
-			type TEXT NOT NULL,
-			path TEXT NOT NULL,
-			metadata_json TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
-			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS settings (
-			key TEXT PRIMARY KEY,
-			value_json TEXT
-		);
-		`,
-		`CREATE INDEX IF NOT EXISTS idx_messages_session_created ON messages(session_id, created_at);`,
-		`CREATE INDEX IF NOT EXISTS idx_tool_calls_session_created ON tool_calls(session_id, created_at);`,
-		`CREATE INDEX IF NOT EXISTS idx_sessions_user_updated ON sessions(user_id, updated_at);`,
-		`INSERT OR IGNORE INTO users (id, name, created_at) VALUES ('default', 'Default User', CURRENT_TIMESTAMP);`,
-	}
-
-	for _, migration := range migrations {
-		if _, err := s.db.Exec(migration); err != nil {
-			return fmt.Errorf("migration failed: %w", err)
-		}
-	}
-
-	return nil
-}
-
-
 func (s *Storage) CreateSession(session *models.Session) error {
 	if session.ID == "" {
 		session.ID = uuid.New().String()
 	}
-	session.CreatedAt = time.Now()
-	session.UpdatedAt = session.CreatedAt
+	// Imported sessions keep their original timestamps.
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	if session.UpdatedAt.IsZero() {
+		session.UpdatedAt = session.CreatedAt
+	}
 
 	_, err := s.db.Exec(
-		`INSERT INTO sessions (id, user_id, title, model, system_prompt, summary, created_at, updated_at) 
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		session.ID, session.UserID, session.Title, session.Model, session.SystemPrompt, session.Summary,
-		session.CreatedAt, session.UpdatedAt,
+		`INSERT INTO sessions (id, user_id, title, model, provider_id, system_prompt, summary, context_summary, summary_until, source_id, workspace, created_at, updated_at) 
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.Title, session.Model, session.ProviderID, session.SystemPrompt, session.Summary,
+		session.ContextSummary, session.SummaryUntil, session.SourceID, session.Workspace, session.CreatedAt, session.UpdatedAt,
 	)
 	return err
 }
@@ -141,11 +68,11 @@ func (s *Storage) CreateSession(session *models.Session) error {
 func (s *Storage) GetSession(id string) (*models.Session, error) {
 	var session models.Session
 	err := s.db.QueryRow(
-		`SELECT id, user_id, title, model, system_prompt, summary, created_at, updated_at, archived_at 
+		`SELECT id, user_id, title, model, provider_id, system_prompt, summary, context_summary, summary_until, source_id, workspace, created_at, updated_at, archived_at 
 		 FROM sessions WHERE id = ?`,
 		id,
-	).Scan(&session.ID, &session.UserID, &session.Title, &session.Model, &session.SystemPrompt,
-		&session.Summary, &session.CreatedAt, &session.UpdatedAt, &session.ArchivedAt)
+	).Scan(&session.ID, &session.UserID, &session.Title, &session.Model, &session.ProviderID, &session.SystemPrompt,
+		&session.Summary, &session.ContextSummary, &session.SummaryUntil, &session.SourceID, &session.Workspace, &session.CreatedAt, &session.UpdatedAt, &session.ArchivedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("session not found: %s", id)
 	}
@@ -154,7 +81,7 @@ func (s *Storage) GetSession(id string) (*models.Session, error) {
 
 func (s *Storage) ListSessions(userID string) ([]models.Session, error) {
 	rows, err := s.db.Query(
-		`SELECT id, user_id, title, model, system_prompt, summary, created_at, updated_at, archived_at 
+		`SELECT id, user_id, title, model, provider_id, system_prompt, summary, context_summary, summary_until, source_id, workspace, created_at, updated_at, archived_at 
 		 FROM sessions WHERE user_id = ? AND archived_at IS NULL ORDER BY updated_at DESC`,
 		userID,
 	)
@@ -166,8 +93,8 @@ func (s *Storage) ListSessions(userID string) ([]models.Session, error) {
 	var sessions []models.Session
 	for rows.Next() {
 		var session models.Session
-		err := rows.Scan(&session.ID, &session.UserID, &session.Title, &session.Model, &session.SystemPrompt,
-			&session.Summary, &session.CreatedAt, &session.UpdatedAt, &session.ArchivedAt)
+		err := rows.Scan(&session.ID, &session.UserID, &session.Title, &session.Model, &session.ProviderID, &session.SystemPrompt,
+			&session.Summary, &session.ContextSummary, &session.SummaryUntil, &session.SourceID, &session.Workspace, &session.CreatedAt, &session.UpdatedAt, &session.ArchivedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -176,11 +103,22 @@ func (s *Storage) ListSessions(userID string) ([]models.Session, error) {
 	return sessions, rows.Err()
 }
 
+// SessionSourceExists reports whether a session imported from sourceID, or
+// with that ID, is already stored.
+func (s *Storage) SessionSourceExists(sourceID string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM sessions WHERE source_id = ? OR id = ?)`,
+		sourceID, sourceID,
+	).Scan(&exists)
+	return exists, err
+}
+
 func (s *Storage) UpdateSession(session *models.Session) error {
 	session.UpdatedAt = time.Now()
 	_, err := s.db.Exec(
-		`UPDATE sessions SET title = ?, model = ?, system_prompt = ?, summary = ?, updated_at = ? WHERE id = ?`,
-		session.Title, session.Model, session.SystemPrompt, session.Summary, session.UpdatedAt, session.ID,
+		`UPDATE sessions SET title = ?, model = ?, provider_id = ?, system_prompt = ?, summary = ?, workspace = ?, updated_at = ? WHERE id = ?`,
+		session.Title, session.Model, session.ProviderID, session.SystemPrompt, session.Summary, session.Workspace, session.UpdatedAt, session.ID,
 	)
 	return err
 }
@@ -190,17 +128,62 @@ func (s *Storage) ArchiveSession(id string) error {
 	return err
 }
 
-func (s *Storage) DeleteSession(id string) error {
-	_, err := s.db.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+func (s *Storage) UnarchiveSession(id string) error {
+	_, err := s.db.Exec(`UPDATE sessions SET archived_at = NULL WHERE id = ?`, id)
 	return err
 }
 
+func (s *Storage) ListArchivedSessions(userID string) ([]models.Session, error) {
+	rows, err := s.db.Query(
+		`SELECT id, user_id, title, model, provider_id, system_prompt, summary, context_summary, summary_until, source_id, workspace, created_at, updated_at, archived_at 
+		 FROM sessions WHERE user_id = ? AND archived_at IS NOT NULL ORDER BY archived_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(&session.ID, &session.UserID, &session.Title, &session.Model, &session.ProviderID, &session.SystemPrompt,
+			&session.Summary, &session.ContextSummary, &session.SummaryUntil, &session.SourceID, &session.Workspace, &session.CreatedAt, &session.UpdatedAt, &session.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *Storage) DeleteSession(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM adk_events WHERE session_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM adk_sessions WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 
 func (s *Storage) CreateMessage(msg *models.Message) error {
 	if msg.ID == "" {
 		msg.ID = uuid.New().String()
 	}
-	msg.CreatedAt = time.Now()
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
 
 	metadataJSON, _ := json.Marshal(msg.Metadata)
 
@@ -273,32 +256,201 @@ func (s *Storage) UpdateMessage(msg *models.Message) error {
 	return err
 }
 
+// RecoverInterruptedMessages marks messages left in_progress, e.g. by a crash
+// during generation, as failed. It returns the number of recovered messages.
+func (s *Storage) RecoverInterruptedMessages() (int, error) {
+	rows, err := s.db.Query(`SELECT id, metadata_json FROM messages WHERE status = ?`, models.StatusInProgress)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	metadata := map[string]map[string]any{}
+	for rows.Next() {
+		var id string
+		var metadataJSON []byte
+		if err := rows.Scan(&id, &metadataJSON); err != nil {
+			return 0, err
+		}
+		var meta map[string]any
+		if len(metadataJSON) > 0 {
+			json.Unmarshal(metadataJSON, &meta)
+		}
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		meta["error"] = "Generation was interrupted before it finished."
+		metadata[id] = meta
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	if len(metadata) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for id, meta := range metadata {
+		metadataJSON, _ := json.Marshal(meta)
+		if _, err := tx.Exec(
+			`UPDATE messages SET status = ?, metadata_json = ? WHERE id = ?`,
+			models.StatusFailed, metadataJSON, id,
+		); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(metadata), nil
+}
+
 func (s *Storage) UpdateSessionTimestamp(sessionID string) error {
 	_, err := s.db.Exec(`UPDATE sessions SET updated_at = ? WHERE id = ?`, time.Now(), sessionID)
 	return err
 }
 
+// UpdateSessionTitle and UpdateSessionSummary change a single column, so a
+// background update cannot overwrite settings edited in the meantime.
+func (s *Storage) UpdateSessionTitle(sessionID, title string) error {
+	_, err := s.db.Exec(`UPDATE sessions SET title = ? WHERE id = ?`, title, sessionID)
+	return err
+}
+
+func (s *Storage) UpdateSessionSummary(sessionID, summary string) error {
+	_, err := s.db.Exec(`UPDATE sessions SET summary = ? WHERE id = ?`, summary, sessionID)
+	return err
+}
+
+// SetContextSummary stores a summary that replaces the session's messages up
+// to and including until when the conversation is sent to the model.
+func (s *Storage) SetContextSummary(sessionID, summary string, until time.Time) error {
+	_, err := s.db.Exec(`UPDATE sessions SET context_summary = ?, summary_until = ? WHERE id = ?`, summary, until, sessionID)
+	return err
+}
+
+// SessionTokenUsage totals the tokens recorded on a session's messages. The
+// context size is taken from the latest reply sent after the context summary.
+func (s *Storage) SessionTokenUsage(sessionID string) (*models.TokenUsage, error) {
+	var usage models.TokenUsage
+	err := s.db.QueryRow(
+		`SELECT COALESCE(SUM(json_extract(metadata_json, '$.prompt_tokens')), 0),
+		        COALESCE(SUM(json_extract(metadata_json, '$.output_tokens')), 0)
+		 FROM messages WHERE session_id = ? AND json_valid(metadata_json)`,
+		sessionID,
+	).Scan(&usage.PromptTokens, &usage.OutputTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	var summaryUntil *time.Time
+	if err := s.db.QueryRow(`SELECT summary_until FROM sessions WHERE id = ?`, sessionID).Scan(&summaryUntil); err != nil {
+		return nil, err
+	}
+
+	var contextTokens int
+	var createdAt time.Time
+	err = s.db.QueryRow(
+		`SELECT json_extract(metadata_json, '$.context_tokens'), created_at
+		 FROM messages WHERE session_id = ? AND json_valid(metadata_json)
+		   AND json_extract(metadata_json, '$.context_tokens') IS NOT NULL
+		 ORDER BY created_at DESC LIMIT 1`,
+		sessionID,
+	).Scan(&contextTokens, &createdAt)
+	if err == sql.ErrNoRows {
+		return &usage, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if summaryUntil == nil || createdAt.After(*summaryUntil) {
+		usage.ContextTokens = contextTokens
+	}
+	return &usage, nil
+}
+
+// UsageRecords groups the token usage recorded on replies since the given
+// time by local day, provider, model and session. Replies recorded before the
+// provider and model were stored with them are attributed to their session's.
+func (s *Storage) UsageRecords(since time.Time) ([]models.UsageRecord, error) {
+	rows, err := s.db.Query(
+		`SELECT date(m.created_at, 'localtime') AS day,
+		        COALESCE(json_extract(m.metadata_json, '$.provider_id'), s.provider_id) AS provider_id,
+		        COALESCE(json_extract(m.metadata_json, '$.model'), s.model) AS model,
+		        s.id, s.title, COUNT(*),
+		        SUM(COALESCE(json_extract(m.metadata_json, '$.prompt_tokens'), 0)),
+		        SUM(COALESCE(json_extract(m.metadata_json, '$.output_tokens'), 0))
+		 FROM messages m JOIN sessions s ON s.id = m.session_id
+		 WHERE json_valid(m.metadata_json) AND m.token_count IS NOT NULL
+		   AND julianday(m.created_at) >= julianday(?)
+		 GROUP BY day, provider_id, model, s.id
+		 ORDER BY day, provider_id, model, s.title`,
+		since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []models.UsageRecord
+	for rows.Next() {
+		var r models.UsageRecord
+		if err := rows.Scan(&r.Day, &r.ProviderID, &r.Model, &r.SessionID, &r.SessionTitle, &r.Replies,
+			&r.PromptTokens, &r.OutputTokens); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (s *Storage) CountMessages(sessionID string) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE session_id = ?`, sessionID).Scan(&count)
+	return count, err
+}
+
 
 func (s *Storage) CreateToolCall(tc *models.ToolCall) error {
 	if tc.ID == "" {
 		tc.ID = uuid.New().String()
 	}
-	tc.CreatedAt = time.Now()
+	if tc.CreatedAt.IsZero() {
+		tc.CreatedAt = time.Now()
+	}
 
 	argsJSON, _ := json.Marshal(tc.Args)
 	resultJSON, _ := json.Marshal(tc.Result)
 
 	_, err := s.db.Exec(
-		`INSERT INTO tool_calls (id, session_id, message_id, tool_name, args_json, result_json, error, created_at) 
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		tc.ID, tc.SessionID, tc.MessageID, tc.ToolName, argsJSON, resultJSON, tc.Error, tc.CreatedAt,
+		`INSERT INTO tool_calls (id, session_id, message_id, tool_name, args_json, result_json, error, duration_ms, created_at) 
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tc.ID, tc.SessionID, tc.MessageID, tc.ToolName, argsJSON, resultJSON, tc.Error, tc.DurationMs, tc.CreatedAt,
+	)
+	return err
+}
+
+func (s *Storage) UpdateToolCall(tc *models.ToolCall) error {
+	argsJSON, _ := json.Marshal(tc.Args)
+	resultJSON, _ := json.Marshal(tc.Result)
+
+	_, err := s.db.Exec(
+		`UPDATE tool_calls SET args_json = ?, result_json = ?, error = ?, duration_ms = ? WHERE id = ?`,
+		argsJSON, resultJSON, tc.Error, tc.DurationMs, tc.ID,
 	)
 	return err
 }
 
 func (s *Storage) ListToolCalls(sessionID string) ([]models.ToolCall, error) {
 	rows, err := s.db.Query(
-		`SELECT id, session_id, message_id, tool_name, args_json, result_json, error, created_at 
+		`SELECT id, session_id, message_id, tool_name, args_json, result_json, error, duration_ms, created_at 
 		 FROM tool_calls WHERE session_id = ? ORDER BY created_at DESC`,
 		sessionID,
 	)
@@ -311,7 +463,7 @@ func (s *Storage) ListToolCalls(sessionID string) ([]models.ToolCall, error) {
 	for rows.Next() {
 		var tc models.ToolCall
 		var argsJSON, resultJSON []byte
-		err := rows.Scan(&tc.ID, &tc.SessionID, &tc.MessageID, &tc.ToolName, &argsJSON, &resultJSON, &tc.Error, &tc.CreatedAt)
+		err := rows.Scan(&tc.ID, &tc.SessionID, &tc.MessageID, &tc.ToolName, &argsJSON, &resultJSON, &tc.Error, &tc.DurationMs, &tc.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -327,6 +479,99 @@ func (s *Storage) ListToolCalls(sessionID string) ([]models.ToolCall, error) {
 }
 
 
+func (s *Storage) CreateAttachment(a *models.Attachment) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+
+	var messageID any
+	if a.MessageID != "" {
+		messageID = a.MessageID
+	}
+	metadataJSON, _ := json.Marshal(a.Metadata)
+
+	_, err := s.db.Exec(
+		`INSERT INTO attachments (id, session_id, message_id, type, path, metadata_json, created_at) 
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, a.SessionID, messageID, a.Type, a.Path, metadataJSON, a.CreatedAt,
+	)
+	return err
+}
+
+func (s *Storage) ListAttachments(sessionID string) ([]models.Attachment, error) {
+	rows, err := s.db.Query(
+		`SELECT id, session_id, COALESCE(message_id, ''), type, path, metadata_json, created_at 
+		 FROM attachments WHERE session_id = ? ORDER BY created_at`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		var a models.Attachment
+		var metadataJSON []byte
+		err := rows.Scan(&a.ID, &a.SessionID, &a.MessageID, &a.Type, &a.Path, &metadataJSON, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if len(metadataJSON) > 0 {
+			json.Unmarshal(metadataJSON, &a.Metadata)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+
+// toolPoliciesKey is the setting holding the per-tool permission policies.
+const toolPoliciesKey = "tool_policies"
+
+// GetToolPolicies returns the permission policy of each tool that has one.
+func (s *Storage) GetToolPolicies() (map[string]models.ToolPolicy, error) {
+	value, err := s.GetSetting(toolPoliciesKey)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]models.ToolPolicy)
+	stored, _ := value.(map[string]any)
+	for name, policy := range stored {
+		if p, ok := policy.(string); ok {
+			policies[name] = models.ToolPolicy(p)
+		}
+	}
+	return policies, nil
+}
+
+// SetToolPolicies replaces the stored tool permission policies.
+func (s *Storage) SetToolPolicies(policies map[string]models.ToolPolicy) error {
+	return s.SetSetting(toolPoliciesKey, policies)
+}
+
+// ListToolNames returns the names of all tools that have been called.
+func (s *Storage) ListToolNames() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT tool_name FROM tool_calls WHERE tool_name != '' ORDER BY tool_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
 func (s *Storage) GetSetting(key string) (any, error) {
 	var valueJSON []byte
 	err := s.db.QueryRow(`SELECT value_json FROM settings WHERE key = ?`, key).Scan(&valueJSON)
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"axe-desktop/pkg/models"
+)
+
+// TestSummariesAreSeparate checks that the sidebar summary, generated in the
+// background, cannot replace the context summary that stands in for the
+// compacted messages.
+func TestSummariesAreSeparate(t *testing.T) {
+	s := newTestStorage(t)
+	session := &models.Session{UserID: "default", Title: "Chat", Model: "m"}
+	if err := s.CreateSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	until := time.Now().UTC().Truncate(time.Second)
+	if err := s.SetContextSummary(session.ID, "Everything so far.\nIn detail.", until); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateSessionSummary(session.ID, "A one-line blurb"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetSession(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Summary == nil || *got.Summary != "A one-line blurb" {
+		t.Errorf("summary = %v", got.Summary)
+	}
+	if got.ContextSummary == nil || *got.ContextSummary != "Everything so far.\nIn detail." {
+		t.Errorf("context summary = %v", got.ContextSummary)
+	}
+	if got.SummaryUntil == nil || !got.SummaryUntil.Equal(until) {
+		t.Errorf("summary until = %v, want %v", got.SummaryUntil, until)
+	}
+}
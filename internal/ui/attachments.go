@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+
+	"axe-desktop/internal/attachment"
+	"axe-desktop/pkg/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	fynestorage "fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+var (
+	thumbnailSize = fyne.NewSize(160, 120)
+	chipIconSize  = fyne.NewSize(24, 24)
+)
+
+// newAttachmentChip shows an attachment's name with a small preview. With
+// onRemove set it gets a remove button, otherwise tapping it opens the file.
+func newAttachmentChip(a models.Attachment, onRemove func()) fyne.CanvasObject {
+	var icon fyne.CanvasObject
+	if a.Type == models.AttachmentImage {
+		img := canvas.NewImageFromFile(a.Path)
+		img.FillMode = canvas.ImageFillContain
+		img.SetMinSize(chipIconSize)
+		icon = img
+	} else {
+		icon = widget.NewIcon(theme.FileIcon())
+	}
+
+	name := widget.NewLabel(truncateLabel(attachment.Name(a), 28))
+	name.SizeName = theme.SizeNameCaptionText
+
+	var action fyne.CanvasObject
+	if onRemove != nil {
+		removeBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), onRemove)
+		removeBtn.Importance = widget.LowImportance
+		action = removeBtn
+	} else {
+		openBtn := widget.NewButtonWithIcon("", theme.FileApplicationIcon(), func() { openAttachment(a) })
+		openBtn.Importance = widget.LowImportance
+		action = openBtn
+	}
+
+	bg := canvas.NewRectangle(VercelDarkGray)
+	bg.CornerRadius = 6
+	bg.StrokeColor = VercelGray
+	bg.StrokeWidth = 1
+
+	return container.NewStack(bg, container.NewHBox(container.NewCenter(icon), name, action))
+}
+
+// newAttachmentView lays out a message's attachments: images as thumbnails
+// and other files as chips.
+func newAttachmentView(attachments []models.Attachment) fyne.CanvasObject {
+	images := container.NewGridWrap(thumbnailSize)
+	files := container.NewVBox()
+	for _, a := range attachments {
+		if a.Type == models.AttachmentImage {
+			images.Add(newThumbnail(a))
+			continue
+		}
+		files.Add(container.NewHBox(newAttachmentChip(a, nil)))
+	}
+
+	box := container.NewVBox()
+	if len(images.Objects) > 0 {
+		box.Add(images)
+	}
+	if len(files.Objects) > 0 {
+		box.Add(files)
+	}
+	return box
+}
+
+// thumbnail is an image preview that opens the full image when tapped.
+type thumbnail struct {
+	widget.BaseWidget
+	attachment models.Attachment
+}
+
+func newThumbnail(a models.Attachment) *thumbnail {
+	t := &thumbnail{attachment: a}
+	t.ExtendBaseWidget(t)
+	return t
+}
+
+func (t *thumbnail) CreateRenderer() fyne.WidgetRenderer {
+	bg := canvas.NewRectangle(VercelBlack)
+	bg.CornerRadius = 6
+	img := canvas.NewImageFromFile(t.attachment.Path)
+	img.FillMode = canvas.ImageFillContain
+	img.ScaleMode = canvas.ImageScaleSmooth
+	return widget.NewSimpleRenderer(container.NewStack(bg, img))
+}
+
+func (t *thumbnail) MinSize() fyne.Size {
+	return thumbnailSize
+}
+
+func (t *thumbnail) Tapped(*fyne.PointEvent) {
+	openAttachment(t.attachment)
+}
+
+func (t *thumbnail) Cursor() desktop.Cursor {
+	return desktop.PointerCursor
+}
+
+// openAttachment opens the file with the system's default application.
+func openAttachment(a models.Attachment) {
+	u, err := url.Parse(fynestorage.NewFileURI(a.Path).String())
+	if err == nil {
+		err = fyne.CurrentApp().OpenURL(u)
+	}
+	if err != nil {
+		fmt.Printf("Failed to open attachment: %v\n", err)
+	}
+}
+
+func truncateLabel(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return string(runes[:maxRunes-3]) + "..."
+}
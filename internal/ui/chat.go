@@ -3,6 +3,8 @@ package ui
 import (
 	"image/color"
 
+	"axe-desktop/pkg/models"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
@@ -13,8 +15,13 @@ import (
 
 type MessageBubble struct {
 	container *fyne.Container
+	bg        *canvas.Rectangle
+	text      string
 	content   *widget.RichText
 	segment   *widget.TextSegment
+	markdown  *MarkdownView
+	// attachments holds the files sent with the message, hidden when empty.
+	attachments *fyne.Container
 }
 
 type StatusLine struct {
@@ -22,8 +29,8 @@ type StatusLine struct {
 	text      *canvas.Text
 }
 
-func NewMessageBubble(role, content string) *MessageBubble {
-	mb := &MessageBubble{}
+func NewMessageBubble(role, content string, window fyne.Window) *MessageBubble {
+	mb := &MessageBubble{text: content}
 
 	roleText := "Assistant"
 	roleColor := VercelMuted
@@ -39,15 +46,23 @@ func NewMessageBubble(role, content string) *MessageBubble {
 	roleLabel.TextSize = theme.Size(ChatMetaSizeName)
 	roleLabel.TextStyle = fyne.TextStyle{Bold: true}
 
-	mb.segment = &widget.TextSegment{
-		Text: content,
-		Style: widget.RichTextStyle{
-			Alignment: fyne.TextAlignLeading,
-			SizeName:  ChatTextSizeName,
-		},
+	// Assistant replies are Markdown; user and system text is shown verbatim.
+	var body fyne.CanvasObject
+	if role == "assistant" {
+		mb.markdown = NewMarkdownView(content, window)
+		body = mb.markdown.Container()
+	} else {
+		mb.segment = &widget.TextSegment{
+			Text: content,
+			Style: widget.RichTextStyle{
+				Alignment: fyne.TextAlignLeading,
+				SizeName:  ChatTextSizeName,
+			},
+		}
+		mb.content = widget.NewRichText(mb.segment)
+		mb.content.Wrapping = fyne.TextWrapWord
+		body = mb.content
 	}
-	mb.content = widget.NewRichText(mb.segment)
-	mb.content.Wrapping = fyne.TextWrapWord
 
 	bg := canvas.NewRectangle(VercelDarkGray)
 	bg.CornerRadius = 8
@@ -55,10 +70,15 @@ func NewMessageBubble(role, content string) *MessageBubble {
 		bg.FillColor = VercelGray
 	}
 	bg.SetMinSize(fyne.NewSize(280, 0))
+	mb.bg = bg
+
+	mb.attachments = container.NewPadded()
+	mb.attachments.Hide()
 
 	contentBox := container.NewVBox(
 		container.NewPadded(roleLabel),
-		container.NewPadded(mb.content),
+		mb.attachments,
+		container.NewPadded(body),
 	)
 
 	bubbleStack := container.NewStack(bg, contentBox)
@@ -91,12 +111,44 @@ func (mb *MessageBubble) GetContainer() fyne.CanvasObject {
 }
 
 func (mb *MessageBubble) UpdateContent(content string) {
+	if content == mb.text {
+		return
+	}
+	mb.text = content
+	if mb.markdown != nil {
+		mb.markdown.SetMarkdown(content)
+		return
+	}
 	mb.segment.Text = content
 	mb.content.Refresh()
 }
 
 func (mb *MessageBubble) Text() string {
-	return mb.segment.Text
+	return mb.text
+}
+
+// SetAttachments shows the files sent with the message above its text.
+func (mb *MessageBubble) SetAttachments(attachments []models.Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+	mb.attachments.Objects = []fyne.CanvasObject{newAttachmentView(attachments)}
+	mb.attachments.Show()
+	if mb.text == "" && mb.content != nil {
+		mb.content.Hide()
+	}
+	mb.attachments.Refresh()
+}
+
+// SetHighlighted outlines the bubble, e.g. to mark a search result.
+func (mb *MessageBubble) SetHighlighted(highlighted bool) {
+	if highlighted {
+		mb.bg.StrokeColor = VercelBlue
+		mb.bg.StrokeWidth = 2
+	} else {
+		mb.bg.StrokeWidth = 0
+	}
+	mb.bg.Refresh()
 }
 
 func NewStatusLine(content string) *StatusLine {
@@ -128,11 +180,14 @@ type ChatView struct {
 	lastAssistant   fyne.CanvasObject
 	adjustingScroll bool
 	lastScroll      fyne.Position
+	window          fyne.Window
+	messageIDs      map[string]*MessageBubble
+	highlighted     *MessageBubble
 }
 
-func NewChatView() *ChatView {
+func NewChatView(window fyne.Window) *ChatView {
 	content := container.NewVBox()
-	cv := &ChatView{messages: content}
+	cv := &ChatView{messages: content, window: window}
 
 	contentWrapper := container.New(&MaxWidthLayout{MaxWidth: 860, MinWidth: 860}, content)
 	centered := container.NewHBox(layout.NewSpacer(), contentWrapper, layout.NewSpacer())
@@ -182,12 +237,19 @@ func (cv *ChatView) Container() fyne.CanvasObject {
 }
 
 func (cv *ChatView) AddMessage(role, content string) {
+	cv.AddStoredMessage("", role, content)
+}
+
+// AddStoredMessage adds a persisted message so ScrollToMessage can find it.
+func (cv *ChatView) AddStoredMessage(id, role, content string) {
 	if len(cv.messageWidgets) > 0 && cv.currentRole == role && role == "assistant" {
 		cv.UpdateLastMessage(content)
+		cv.trackID(id, cv.messageWidgets[len(cv.messageWidgets)-1])
 		return
 	}
 
-	bubble := NewMessageBubble(role, content)
+	bubble := NewMessageBubble(role, content, cv.window)
+	cv.trackID(id, bubble)
 	cv.messages.Add(bubble.GetContainer())
 	cv.messageWidgets = append(cv.messageWidgets, bubble)
 	cv.currentRole = role
@@ -197,9 +259,53 @@ func (cv *ChatView) AddMessage(role, content string) {
 	cv.scrollContainer.ScrollToBottom()
 }
 
+// SetLastAttachments shows attachments on the most recent message.
+func (cv *ChatView) SetLastAttachments(attachments []models.Attachment) {
+	if len(cv.messageWidgets) == 0 {
+		return
+	}
+	cv.messageWidgets[len(cv.messageWidgets)-1].SetAttachments(attachments)
+	cv.scrollContainer.ScrollToBottom()
+}
+
+func (cv *ChatView) trackID(id string, bubble *MessageBubble) {
+	if id == "" {
+		return
+	}
+	if cv.messageIDs == nil {
+		cv.messageIDs = make(map[string]*MessageBubble)
+	}
+	cv.messageIDs[id] = bubble
+}
+
+// ScrollToMessage scrolls a stored message into view and highlights it. It
+// reports whether the message is shown.
+func (cv *ChatView) ScrollToMessage(id string) bool {
+	bubble, ok := cv.messageIDs[id]
+	if !ok {
+		return false
+	}
+
+	if cv.highlighted != nil {
+		cv.highlighted.SetHighlighted(false)
+	}
+	bubble.SetHighlighted(true)
+	cv.highlighted = bubble
+
+	// Lay out first so the bubble's position reflects the loaded messages.
+	cv.scrollContainer.Refresh()
+	y := bubble.GetContainer().Position().Y
+	cv.adjustingScroll = true
+	cv.scrollContainer.ScrollToOffset(fyne.NewPos(0, y))
+	cv.adjustingScroll = false
+	return true
+}
+
 func (cv *ChatView) Clear() {
 	cv.messages.Objects = nil
 	cv.messageWidgets = nil
+	cv.messageIDs = nil
+	cv.highlighted = nil
 	cv.currentRole = ""
 	cv.statusLine = nil
 	cv.lastAssistant = nil
@@ -271,6 +377,23 @@ func (cv *ChatView) AddNote(content string) {
 	cv.scrollContainer.ScrollToBottom()
 }
 
+// AddRetry shows a failure line with a Retry button below the last message.
+// The button is disabled once tapped.
+func (cv *ChatView) AddRetry(content string, onRetry func()) {
+	text := canvas.NewText(content, VercelMuted)
+	text.TextSize = theme.Size(ChatMetaSizeName)
+
+	var retryBtn *widget.Button
+	retryBtn = widget.NewButtonWithIcon("Retry", theme.ViewRefreshIcon(), func() {
+		retryBtn.Disable()
+		onRetry()
+	})
+	retryBtn.Importance = widget.LowImportance
+
+	cv.messages.Add(container.NewHBox(widget.NewIcon(theme.NewErrorThemedResource(theme.ErrorIcon())), text, retryBtn))
+	cv.scrollContainer.ScrollToBottom()
+}
+
 func (cv *ChatView) insertBeforeAssistant(obj fyne.CanvasObject) {
 	if cv.lastAssistant == nil {
 		cv.messages.Add(obj)
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// CodeBlock shows a fenced code block with syntax highlighting and buttons to
+// copy the code or save it to a file.
+type CodeBlock struct {
+	container *fyne.Container
+	code      *widget.RichText
+	langLabel *canvas.Text
+	text      string
+	lang      string
+	window    fyne.Window
+}
+
+func NewCodeBlock(text, lang string, window fyne.Window) *CodeBlock {
+	cb := &CodeBlock{window: window}
+
+	cb.code = widget.NewRichText()
+	cb.code.Wrapping = fyne.TextWrapBreak
+
+	cb.langLabel = canvas.NewText("", VercelMuted)
+	cb.langLabel.TextSize = theme.Size(ChatMetaSizeName)
+	cb.langLabel.TextStyle = fyne.TextStyle{Monospace: true}
+
+	var copyBtn *widget.Button
+	copyBtn = widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), func() {
+		fyne.CurrentApp().Clipboard().SetContent(cb.text)
+		copyBtn.SetText("Copied")
+		go func() {
+			time.Sleep(2 * time.Second)
+			fyne.Do(func() { copyBtn.SetText("Copy") })
+		}()
+	})
+	copyBtn.Importance = widget.LowImportance
+
+	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), cb.save)
+	saveBtn.Importance = widget.LowImportance
+
+	header := container.NewBorder(nil, nil, container.NewPadded(cb.langLabel), container.NewHBox(copyBtn, saveBtn))
+
+	bg := canvas.NewRectangle(VercelBlack)
+	bg.CornerRadius = 6
+	bg.StrokeColor = VercelGray
+	bg.StrokeWidth = 1
+
+	cb.container = container.NewStack(bg, container.NewBorder(header, nil, nil, nil, container.NewPadded(cb.code)))
+	cb.SetCode(text, lang)
+	return cb
+}
+
+func (cb *CodeBlock) Container() fyne.CanvasObject {
+	return cb.container
+}
+
+// SetCode updates the block, re-rendering only the segments that changed.
+func (cb *CodeBlock) SetCode(text, lang string) {
+	if lang != cb.lang {
+		cb.code.Segments = nil
+	}
+	cb.text = text
+	cb.lang = lang
+
+	label := lang
+	if label == "" {
+		label = "code"
+	}
+	if cb.langLabel.Text != label {
+		cb.langLabel.Text = label
+		cb.langLabel.Refresh()
+	}
+
+	cb.code.Segments = mergeSegments(cb.code.Segments, highlightCode(text, lang))
+	cb.code.Refresh()
+}
+
+func (cb *CodeBlock) save() {
+	if cb.window == nil {
+		return
+	}
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, cb.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write([]byte(cb.text)); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save code: %w", err), cb.window)
+		}
+	}, cb.window)
+	d.SetFileName("snippet" + codeFileExtension(cb.lang))
+	d.Show()
+}
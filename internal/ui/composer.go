@@ -1,6 +1,11 @@
 package ui
 
 import (
+	"fmt"
+
+	"axe-desktop/internal/attachment"
+	"axe-desktop/pkg/models"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
@@ -10,21 +15,63 @@ import (
 )
 
 type Composer struct {
-	onSubmit func(string)
-	entry    *widget.Entry
-	sendBtn  *widget.Button
+	onSubmit  func(string, []models.Attachment)
+	onStop    func()
+	entry     *composerEntry
+	sendBtn   *widget.Button
+	stopBtn   *widget.Button
+	attachBtn *widget.Button
+	usage     *widget.Label
+	streaming bool
+
+	// attachments are saved files waiting to be sent with the next message.
+	attachments []models.Attachment
+	attachRow   *fyne.Container
+}
+
+// composerEntry is a multi-line entry that reports Escape so a running
+// generation can be stopped from the keyboard, and pastes without clipboard
+// text so an image can be pasted instead.
+type composerEntry struct {
+	widget.Entry
+	onEscape     func()
+	onPasteImage func()
+}
+
+func newComposerEntry() *composerEntry {
+	e := &composerEntry{}
+	e.MultiLine = true
+	e.ExtendBaseWidget(e)
+	return e
 }
 
-func NewComposer(onSubmit func(string)) *Composer {
-	c := &Composer{onSubmit: onSubmit}
+func (e *composerEntry) TypedKey(key *fyne.KeyEvent) {
+	if key.Name == fyne.KeyEscape && e.onEscape != nil {
+		e.onEscape()
+		return
+	}
+	e.Entry.TypedKey(key)
+}
+
+func (e *composerEntry) TypedShortcut(shortcut fyne.Shortcut) {
+	if paste, ok := shortcut.(*fyne.ShortcutPaste); ok && e.onPasteImage != nil && paste.Clipboard.Content() == "" {
+		e.onPasteImage()
+		return
+	}
+	e.Entry.TypedShortcut(shortcut)
+}
 
-	c.entry = widget.NewMultiLineEntry()
+func NewComposer(onSubmit func(string, []models.Attachment), onAttach func(), onStop func()) *Composer {
+	c := &Composer{onSubmit: onSubmit, onStop: onStop}
+
+	c.entry = newComposerEntry()
 	c.entry.SetPlaceHolder("Message...")
 	c.entry.Wrapping = fyne.TextWrapWord
 	c.entry.SetMinRowsVisible(2)
 	c.entry.OnSubmitted = func(_ string) {
 		c.send()
 	}
+	c.entry.onEscape = c.stop
 
 	c.sendBtn = widget.NewButtonWithIcon("", theme.MailSendIcon(), func() {
 		c.send()
@@ -32,12 +79,26 @@ func NewComposer(onSubmit func(string)) *Composer {
 	c.sendBtn.Importance = widget.HighImportance
 	c.sendBtn.Disable()
 
-	c.entry.OnChanged = func(content string) {
-		if content == "" {
-			c.sendBtn.Disable()
-			return
-		}
-		c.sendBtn.Enable()
+	c.stopBtn = widget.NewButtonWithIcon("", theme.MediaStopIcon(), func() {
+		c.stop()
+	})
+	c.stopBtn.Importance = widget.DangerImportance
+	c.stopBtn.Hide()
+
+	c.attachBtn = widget.NewButtonWithIcon("", theme.MailAttachmentIcon(), onAttach)
+	c.attachBtn.Importance = widget.LowImportance
+
+	c.attachRow = container.NewHBox()
+	c.attachRow.Hide()
+
+	c.usage = widget.NewLabel("")
+	c.usage.Alignment = fyne.TextAlignTrailing
+	c.usage.Importance = widget.LowImportance
+	c.usage.SizeName = theme.SizeNameCaptionText
+	c.usage.Hide()
+
+	c.entry.OnChanged = func(string) {
+		c.updateSendButton()
 	}
 
 	return c
@@ -50,32 +111,121 @@ func (c *Composer) Container() fyne.CanvasObject {
 	bg.StrokeWidth = 1
 	bg.SetMinSize(fyne.NewSize(860, 64))
 
-	buttonWrap := container.NewGridWrap(fyne.NewSize(48, 48), c.sendBtn)
-	inputSurface := container.NewBorder(nil, nil, nil, buttonWrap, c.entry)
+	buttonWrap := container.NewGridWrap(fyne.NewSize(48, 48), container.NewStack(c.sendBtn, c.stopBtn))
+	attachWrap := container.NewVBox(layout.NewSpacer(), c.attachBtn)
+	inputSurface := container.NewBorder(container.NewHScroll(c.attachRow), nil, attachWrap, buttonWrap, c.entry)
 
 	bar := container.NewStack(bg, container.NewPadded(inputSurface))
-	maxWidth := container.New(&MaxWidthLayout{MaxWidth: 860, MinWidth: 860}, bar)
+	maxWidth := container.New(&MaxWidthLayout{MaxWidth: 860, MinWidth: 860}, container.NewVBox(bar, c.usage))
 
 	return container.NewHBox(layout.NewSpacer(), maxWidth, layout.NewSpacer())
 }
 
+// SetUsage shows the session's token usage under the input, or hides it when
+// text is empty.
+func (c *Composer) SetUsage(text string) {
+	c.usage.SetText(text)
+	if text == "" {
+		c.usage.Hide()
+		return
+	}
+	c.usage.Show()
+}
+
 func (c *Composer) SetEnabled(enabled bool) {
 	if enabled {
 		c.entry.Enable()
-		if c.entry.Text != "" {
-			c.sendBtn.Enable()
-		}
+		c.attachBtn.Enable()
+		c.updateSendButton()
 	} else {
 		c.entry.Disable()
+		c.attachBtn.Disable()
+		c.sendBtn.Disable()
+	}
+}
+
+// SetStreaming swaps the send button for a stop button while a response is
+// being generated. The entry stays editable so the next message can be drafted.
+func (c *Composer) SetStreaming(streaming bool) {
+	c.streaming = streaming
+	if streaming {
+		c.sendBtn.Hide()
 		c.sendBtn.Disable()
+		c.stopBtn.Show()
+		return
+	}
+	c.stopBtn.Hide()
+	c.sendBtn.Show()
+	c.updateSendButton()
+}
+
+// SetOnPasteImage sets the handler for pastes when the clipboard holds no
+// text.
+func (c *Composer) SetOnPasteImage(onPasteImage func()) {
+	c.entry.onPasteImage = onPasteImage
+}
+
+// AddAttachment queues a saved file to be sent with the next message.
+func (c *Composer) AddAttachment(a models.Attachment) {
+	c.attachments = append(c.attachments, a)
+	c.refreshAttachments()
+	c.updateSendButton()
+}
+
+// removeAttachment drops a queued attachment and deletes its file, which is
+// not referenced anywhere until the message is sent.
+func (c *Composer) removeAttachment(id string) {
+	for i, a := range c.attachments {
+		if a.ID != id {
+			continue
+		}
+		if err := attachment.Remove(a); err != nil {
+			fmt.Printf("Failed to remove attachment: %v\n", err)
+		}
+		c.attachments = append(c.attachments[:i], c.attachments[i+1:]...)
+		break
 	}
+	c.refreshAttachments()
+	c.updateSendButton()
+}
+
+func (c *Composer) refreshAttachments() {
+	c.attachRow.Objects = nil
+	for _, a := range c.attachments {
+		id := a.ID
+		c.attachRow.Add(newAttachmentChip(a, func() { c.removeAttachment(id) }))
+	}
+	if len(c.attachments) == 0 {
+		c.attachRow.Hide()
+	} else {
+		c.attachRow.Show()
+	}
+	c.attachRow.Refresh()
+}
+
+func (c *Composer) updateSendButton() {
+	if c.streaming || c.entry.Disabled() || (c.entry.Text == "" && len(c.attachments) == 0) {
+		c.sendBtn.Disable()
+		return
+	}
+	c.sendBtn.Enable()
 }
 
 func (c *Composer) send() {
 	content := c.entry.Text
-	if content == "" {
+	if (content == "" && len(c.attachments) == 0) || c.streaming {
 		return
 	}
-	c.onSubmit(content)
+	attachments := c.attachments
+	c.attachments = nil
+	c.refreshAttachments()
+	c.onSubmit(content, attachments)
 	c.entry.SetText("")
 }
+
+func (c *Composer) stop() {
+	if !c.streaming || c.onStop == nil {
+		return
+	}
+	c.onStop()
+}
@@ -0,0 +1,291 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// codeLanguage describes just enough of a language's lexical syntax to color
+// keywords, types, strings, numbers and comments.
+type codeLanguage struct {
+	extension    string
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+	keywords     map[string]bool
+	types        map[string]bool
+}
+
+func words(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	cKeywords  = "if else for while do switch case default break continue return goto sizeof typedef struct union enum static const extern volatile inline"
+	cTypes     = "void char short int long float double signed unsigned bool size_t int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t uint64_t"
+	jsKeywords = "async await break case catch class const continue debugger default delete do else export extends finally for from function if import in instanceof let new of return static super switch this throw try typeof var void while yield true false null undefined"
+)
+
+var codeLanguages = map[string]*codeLanguage{
+	"go": {
+		extension:    ".go",
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var true false nil iota"),
+		types:        words("bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr any"),
+	},
+	"python": {
+		extension:    ".py",
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords:     words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield True False None self"),
+		types:        words("int float str bool list dict set tuple bytes object type"),
+	},
+	"javascript": {
+		extension:    ".js",
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords:     words(jsKeywords),
+		types:        words("Array Boolean Date Error Map Number Object Promise RegExp Set String"),
+	},
+	"typescript": {
+		extension:    ".ts",
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords:     words(jsKeywords + " interface type enum implements private protected public readonly abstract declare namespace as is keyof"),
+		types:        words("any unknown never string number boolean bigint symbol object void Array Map Promise Record Set"),
+	},
+	"rust": {
+		extension:    ".rs",
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"",
+		keywords:     words("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while true false"),
+		types:        words("bool char str String i8 i16 i32 i64 i128 isize u8 u16 u32 u64 u128 usize f32 f64 Vec Option Result Box Some None Ok Err"),
+	},
+	"java": {
+		extension:    ".java",
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords:     words("abstract assert break case catch class const continue default do else enum extends final finally for if implements import instanceof interface native new package private protected public return static super switch synchronized this throw throws try volatile while true false null var record"),
+		types:        words("boolean byte char double float int long short void String Object Integer List Map"),
+	},
+	"c": {
+		extension:    ".c",
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords:     words(cKeywords + " NULL"),
+		types:        words(cTypes),
+	},
+	"cpp": {
+		extension:    ".cpp",
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords:     words(cKeywords + " class namespace template typename public private protected virtual override new delete this using auto constexpr nullptr true false try catch throw"),
+		types:        words(cTypes + " string vector map unique_ptr shared_ptr"),
+	},
+	"bash": {
+		extension:    ".sh",
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords:     words("if then else elif fi for while until do done case esac function in return export local readonly set unset echo exit source"),
+	},
+	"sql": {
+		extension:    ".sql",
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"",
+		keywords:     words("select from where and or not insert into values update set delete create table index view drop alter add column primary key foreign references join left right inner outer on group by order having limit offset as distinct union all null is in like between case when then else end exists default SELECT FROM WHERE AND OR NOT INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE INDEX VIEW DROP ALTER ADD COLUMN PRIMARY KEY FOREIGN REFERENCES JOIN LEFT RIGHT INNER OUTER ON GROUP BY ORDER HAVING LIMIT OFFSET AS DISTINCT UNION ALL NULL IS IN LIKE BETWEEN CASE WHEN THEN ELSE END EXISTS DEFAULT"),
+		types:        words("integer int text real blob boolean datetime varchar INTEGER INT TEXT REAL BLOB BOOLEAN DATETIME VARCHAR"),
+	},
+	"json": {
+		extension: ".json",
+		quotes:    "\"",
+		keywords:  words("true false null"),
+	},
+	"yaml": {
+		extension:    ".yaml",
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords:     words("true false null yes no"),
+	},
+}
+
+var languageAliases = map[string]string{
+	"golang": "go",
+	"py":     "python",
+	"js":     "javascript",
+	"jsx":    "javascript",
+	"ts":     "typescript",
+	"tsx":    "typescript",
+	"rs":     "rust",
+	"h":      "c",
+	"c++":    "cpp",
+	"cc":     "cpp",
+	"hpp":    "cpp",
+	"sh":     "bash",
+	"shell":  "bash",
+	"zsh":    "bash",
+	"yml":    "yaml",
+}
+
+func lookupLanguage(lang string) *codeLanguage {
+	lang = strings.ToLower(strings.Fields(lang + " ")[0])
+	if alias, ok := languageAliases[lang]; ok {
+		lang = alias
+	}
+	return codeLanguages[lang]
+}
+
+// codeFileExtension returns the file extension used when saving a code block.
+func codeFileExtension(lang string) string {
+	if l := lookupLanguage(lang); l != nil {
+		return l.extension
+	}
+	return ".txt"
+}
+
+// highlightCode splits code into monospace segments colored by token type.
+// Unknown languages are returned as a single plain segment.
+func highlightCode(code, lang string) []widget.RichTextSegment {
+	l := lookupLanguage(lang)
+	if l == nil {
+		return []widget.RichTextSegment{codeSegment(code, theme.ColorNameForeground)}
+	}
+
+	var segments []widget.RichTextSegment
+	var plain strings.Builder
+	emit := func(text string, color fyne.ThemeColorName) {
+		if plain.Len() > 0 {
+			segments = append(segments, codeSegment(plain.String(), theme.ColorNameForeground))
+			plain.Reset()
+		}
+		segments = append(segments, codeSegment(text, color))
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+
+		if l.blockComment[0] != "" && strings.HasPrefix(rest, l.blockComment[0]) {
+			end := strings.Index(rest[len(l.blockComment[0]):], l.blockComment[1])
+			n := len(rest)
+			if end >= 0 {
+				n = len(l.blockComment[0]) + end + len(l.blockComment[1])
+			}
+			emit(rest[:n], CodeCommentColorName)
+			i += n
+			continue
+		}
+
+		if hasAnyPrefix(rest, l.lineComments) {
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			emit(rest[:n], CodeCommentColorName)
+			i += n
+			continue
+		}
+
+		c := rest[0]
+		if strings.IndexByte(l.quotes, c) >= 0 {
+			n := stringLength(rest)
+			emit(rest[:n], CodeStringColorName)
+			i += n
+			continue
+		}
+
+		if isDigit(c) {
+			n := 1
+			for n < len(rest) && (isIdentByte(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			emit(rest[:n], CodeNumberColorName)
+			i += n
+			continue
+		}
+
+		if isIdentByte(c) {
+			n := 1
+			for n < len(rest) && isIdentByte(rest[n]) {
+				n++
+			}
+			word := rest[:n]
+			switch {
+			case l.keywords[word]:
+				emit(word, CodeKeywordColorName)
+			case l.types[word]:
+				emit(word, CodeTypeColorName)
+			default:
+				plain.WriteString(word)
+			}
+			i += n
+			continue
+		}
+
+		plain.WriteByte(c)
+		i++
+	}
+	if plain.Len() > 0 {
+		segments = append(segments, codeSegment(plain.String(), theme.ColorNameForeground))
+	}
+	if len(segments) == 0 {
+		segments = append(segments, codeSegment("", theme.ColorNameForeground))
+	}
+	return segments
+}
+
+func codeSegment(text string, color fyne.ThemeColorName) *widget.TextSegment {
+	style := widget.RichTextStyleCodeInline
+	style.ColorName = color
+	return &widget.TextSegment{Text: text, Style: style}
+}
+
+// stringLength returns the length of the string literal at the start of s,
+// honouring backslash escapes. Unterminated strings end at the line break,
+// except for backtick strings which may span lines.
+func stringLength(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == quote:
+			return i + 1
+		case s[i] == '\n' && quote != '`':
+			return i
+		}
+	}
+	return len(s)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 0x80 || unicode.IsLetter(rune(c)) || isDigit(c)
+}
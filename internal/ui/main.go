@@ -2,16 +2,29 @@ package ui
 
 import (
 	"axe-desktop/internal/agent"
+	"axe-desktop/internal/attachment"
+	"axe-desktop/internal/clipboard"
 	"axe-desktop/internal/config"
+	"axe-desktop/internal/export"
+	"axe-desktop/internal/importer"
 	"axe-desktop/internal/storage"
+	"axe-desktop/internal/workspace"
 	"axe-desktop/pkg/models"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	fynestorage "fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -21,11 +34,19 @@ type MainUI struct {
 	config       *config.Config
 	agentService *agent.Service
 
-	sidebar  *Sidebar
-	chatView *ChatView
-	composer *Composer
+	sidebar     *Sidebar
+	chatView    *ChatView
+	composer    *Composer
+	toolPanel   *ToolPanel
+	usageWindow *UsageWindow
+	streams     *streamCoordinator
 
 	currentSessionID string
+	// focusMessageID is the message to scroll to once its session is loaded.
+	focusMessageID string
+	// summarizedAt records how many messages a session had when its summary
+	// was last generated.
+	summarizedAt map[string]int
 }
 
 func New(window fyne.Window, store *storage.Storage, cfg *config.Config, agentSvc *agent.Service) *MainUI {
@@ -34,13 +55,23 @@ func New(window fyne.Window, store *storage.Storage, cfg *config.Config, agentSv
 		storage:      store,
 		config:       cfg,
 		agentService: agentSvc,
+		summarizedAt: make(map[string]int),
 	}
 }
 
 func (ui *MainUI) Initialize() {
-	ui.sidebar = NewSidebar(ui.storage, ui.onSessionSelected, ui.onNewSession, ui.onDeleteSession)
-	ui.chatView = NewChatView()
-	ui.composer = NewComposer(ui.onSendMessage)
+	ui.sidebar = NewSidebar(ui.storage, ui.onSessionSelected, ui.onNewSession, ui.onDeleteSession, ui.onArchiveSession,
+		ui.showArchivedDialog, ui.showSessionSettingsDialog, ui.onSearchResult)
+	ui.sidebar.SetContextMenu(ui.sessionContextMenu)
+	ui.chatView = NewChatView(ui.window)
+	ui.composer = NewComposer(ui.onSendMessage, ui.showAttachDialog, ui.onStopGeneration)
+	ui.composer.SetOnPasteImage(ui.pasteImage)
+	ui.toolPanel = NewToolPanel()
+	ui.agentService.SetApprovalHandler(ui.approveToolCall)
+	ui.agentService.SetToolOutputHandler(func(sessionID, toolCallID, output string) {
+		fyne.Do(func() { ui.toolPanel.AppendToolOutput(toolCallID, output) })
+	})
+	ui.streams = newStreamCoordinator(func() string { return ui.currentSessionID }, ui.chatView, ui.composer, ui.sidebar)
 
 	centralColumn := container.NewBorder(
 		nil,
@@ -49,45 +80,217 @@ func (ui *MainUI) Initialize() {
 		ui.chatView.Container(),
 	)
 
-	content := container.NewHSplit(ui.sidebar.Container(), centralColumn)
+	mainArea := container.NewHSplit(centralColumn, ui.toolPanel.Container())
+	mainArea.SetOffset(0.76)
+
+	content := container.NewHSplit(ui.sidebar.Container(), mainArea)
 	content.SetOffset(0.15)
 
 	ui.window.SetContent(content)
 	ui.window.SetMainMenu(ui.createMenu())
+	ui.window.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		for _, uri := range uris {
+			ui.attachFile(uri.Path())
+		}
+	})
+	ui.window.Canvas().SetOnTypedKey(func(key *fyne.KeyEvent) {
+		if key.Name == fyne.KeyEscape {
+			ui.onStopGeneration()
+		}
+	})
 
 	ui.sidebar.LoadSessions("default")
 }
 
 func (ui *MainUI) createMenu() *fyne.MainMenu {
+	exportItem := fyne.NewMenuItem("Export Session", nil)
+	exportItem.ChildMenu = fyne.NewMenu("", ui.exportMenuItems(func() string { return ui.currentSessionID })...)
+
 	return fyne.NewMainMenu(
 		fyne.NewMenu("File",
 			fyne.NewMenuItem("New Session", ui.onNewSession),
+			fyne.NewMenuItem("Import...", ui.showImportDialog),
+			exportItem,
 			fyne.NewMenuItemSeparator(),
 			fyne.NewMenuItem("Quit", func() {
 				ui.window.Close()
 			}),
 		),
+		fyne.NewMenu("Session",
+			fyne.NewMenuItem("Session Settings...", func() {
+				ui.showSessionSettingsDialog(ui.currentSessionID)
+			}),
+			fyne.NewMenuItem("Archive Session", func() {
+				ui.onArchiveSession(ui.currentSessionID)
+			}),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Archived Chats...", ui.showArchivedDialog),
+		),
 		fyne.NewMenu("Settings",
 			fyne.NewMenuItem("Providers & MCP", ui.showSettingsDialog),
+			fyne.NewMenuItem("Tool Permissions...", ui.showToolPermissionsDialog),
+			fyne.NewMenuItem("Usage & Costs...", ui.showUsage),
 		),
 	)
 }
 
+// sessionContextMenu is shown when a session in the sidebar is right-clicked.
+func (ui *MainUI) sessionContextMenu(sessionID string) *fyne.Menu {
+	exportItem := fyne.NewMenuItem("Export", nil)
+	exportItem.ChildMenu = fyne.NewMenu("", ui.exportMenuItems(func() string { return sessionID })...)
+
+	return fyne.NewMenu("",
+		fyne.NewMenuItem("Session Settings...", func() { ui.showSessionSettingsDialog(sessionID) }),
+		exportItem,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Archive", func() { ui.onArchiveSession(sessionID) }),
+		fyne.NewMenuItem("Delete", func() { ui.onDeleteSession(sessionID) }),
+	)
+}
+
+func (ui *MainUI) exportMenuItems(sessionID func() string) []*fyne.MenuItem {
+	items := make([]*fyne.MenuItem, len(export.Formats))
+	for i, format := range export.Formats {
+		items[i] = fyne.NewMenuItem(format.Label()+"...", func() {
+			ui.exportSession(sessionID(), format)
+		})
+	}
+	return items
+}
+
+func (ui *MainUI) exportSession(sessionID string, format export.Format) {
+	if sessionID == "" {
+		dialog.ShowInformation("Export Session", "Select a session first.", ui.window)
+		return
+	}
+
+	conv, err := export.Load(ui.storage, sessionID)
+	if err != nil {
+		dialog.ShowError(err, ui.window)
+		return
+	}
+
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if err := export.Write(writer, conv, format); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to export session: %w", err), ui.window)
+		}
+	}, ui.window)
+	d.SetFileName(export.FileName(conv.Session, format))
+	d.Show()
+}
+
+// showImportDialog imports conversations from an axe-desktop JSON export or
+// a ChatGPT or Claude data export.
+func (ui *MainUI) showImportDialog() {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to read %s: %w", reader.URI().Name(), err), ui.window)
+			return
+		}
+
+		result, err := importer.Import(ui.storage, reader.URI().Name(), data)
+		if result != nil && len(result.Imported) > 0 {
+			ui.sidebar.LoadSessions("default")
+		}
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+
+		summary := fmt.Sprintf("Imported %d conversation(s).", len(result.Imported))
+		if result.Skipped > 0 {
+			summary += fmt.Sprintf("\nSkipped %d already imported.", result.Skipped)
+		}
+		if result.Empty > 0 {
+			summary += fmt.Sprintf("\nSkipped %d without messages.", result.Empty)
+		}
+		dialog.ShowInformation("Import", summary, ui.window)
+	}, ui.window)
+	d.SetFilter(fynestorage.NewExtensionFileFilter([]string{".json", ".zip"}))
+	d.Show()
+}
+
 func (ui *MainUI) onSessionSelected(sessionID string) {
 	ui.currentSessionID = sessionID
 	ui.chatView.Clear()
+	ui.updateUsage(sessionID)
 
-	messages, err := ui.storage.ListMessages(sessionID, 100, 0)
+	focusID := ui.focusMessageID
+	ui.focusMessageID = ""
+
+	// Jumping to a search result loads the whole history so older messages
+	// can be shown.
+	limit := 100
+	if focusID != "" {
+		limit = 0
+	}
+	messages, err := ui.storage.ListMessages(sessionID, limit, 0)
 	if err != nil {
 		return
 	}
+	attachments, err := ui.storage.ListAttachments(sessionID)
+	if err != nil {
+		fmt.Printf("Failed to load attachments: %v\n", err)
+	}
+	byMessage := make(map[string][]models.Attachment)
+	for _, a := range attachments {
+		byMessage[a.MessageID] = append(byMessage[a.MessageID], a)
+	}
 
+	var prompt string
+	var promptAttachments []models.Attachment
 	for i := len(messages) - 1; i >= 0; i-- {
 		msg := messages[i]
-		ui.chatView.AddMessage(string(msg.Role), msg.Content)
+		if msg.Role == models.RoleUser {
+			prompt = msg.Content
+			promptAttachments = byMessage[msg.ID]
+		}
+		if msg.Status == models.StatusFailed {
+			if msg.Content != "" {
+				ui.chatView.AddStoredMessage(msg.ID, string(msg.Role), msg.Content)
+			}
+			ui.addRetry(failureText(msg), prompt, promptAttachments)
+			continue
+		}
+		ui.chatView.AddStoredMessage(msg.ID, string(msg.Role), msg.Content)
+		ui.chatView.SetLastAttachments(byMessage[msg.ID])
 	}
 
 	ui.chatView.ClearStatus()
+	ui.streams.Show(sessionID)
+
+	calls, err := ui.storage.ListToolCalls(sessionID)
+	if err != nil {
+		fmt.Printf("Failed to load tool calls: %v\n", err)
+	}
+	ui.toolPanel.UpdateToolCalls(calls)
+
+	if focusID != "" {
+		ui.chatView.ScrollToMessage(focusID)
+	}
+}
+
+func (ui *MainUI) onSearchResult(result models.SearchResult) {
+	ui.focusMessageID = result.MessageID
+	ui.sidebar.SelectSession(result.SessionID)
 }
 
 func (ui *MainUI) onNewSession() {
@@ -100,21 +303,25 @@ func (ui *MainUI) onNewSession() {
 	secondaryBtn := widget.NewButton("Cancel", nil)
 	secondaryBtn.Importance = widget.LowImportance
 
+	workspaceField, workspaceRoot := ui.newWorkspaceField("")
+
 	content := container.NewVBox(
 		widget.NewLabelWithStyle("New Session", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		nameEntry,
+		widget.NewLabel("Workspace Folder"),
+		workspaceField,
 		container.NewHBox(layout.NewSpacer(), secondaryBtn, primaryBtn),
 	)
 
 	d := dialog.NewCustomWithoutButtons("", content, ui.window)
-	d.Resize(fyne.NewSize(400, 180))
+	d.Resize(fyne.NewSize(480, 260))
 
 	secondaryBtn.OnTapped = func() { d.Hide() }
 
 	primaryBtn.OnTapped = func() {
 		title := nameEntry.Text
 		if title == "" {
-			title = "New Chat"
+			title = untitledSession
 		}
 
 		provider := ui.config.GetActiveProvider()
@@ -124,7 +331,8 @@ func (ui *MainUI) onNewSession() {
 			Title:        title,
 			Model:        provider.Model,
 			ProviderID:   provider.ID,
-			SystemPrompt: "You are a helpful AI assistant.",
+			SystemPrompt: models.DefaultSystemPrompt,
+			Workspace:    workspaceRoot(),
 		}
 
 		if err := ui.storage.CreateSession(session); err != nil {
@@ -152,15 +360,18 @@ func (ui *MainUI) onDeleteSession(sessionID string) {
 			if !ok {
 				return
 			}
-			if err := ui.storage.DeleteSession(sessionID); err != nil {
+			if err := ui.deleteSession(sessionID); err != nil {
 				dialog.ShowError(err, ui.window)
 				return
 			}
 			if ui.currentSessionID == sessionID {
 				ui.currentSessionID = ""
 				ui.chatView.Clear()
-				ui.agentService.RemoveRunner(sessionID)
+				ui.composer.SetUsage("")
+				ui.composer.SetStreaming(false)
+				ui.toolPanel.UpdateToolCalls(nil)
 			}
+			ui.sidebar.ClearSelection()
 			ui.sidebar.LoadSessions("default")
 		},
 		ui.window,
@@ -168,32 +379,202 @@ func (ui *MainUI) onDeleteSession(sessionID string) {
 	confirm.Show()
 }
 
+// showUsage opens the Usage window, or brings it to the front with fresh
+// numbers if it is already open.
+func (ui *MainUI) showUsage() {
+	if ui.usageWindow != nil {
+		ui.usageWindow.Refresh()
+		ui.usageWindow.Show()
+		return
+	}
+	ui.usageWindow = NewUsageWindow(ui.storage, ui.config)
+	ui.usageWindow.SetOnClosed(func() { ui.usageWindow = nil })
+	ui.usageWindow.Show()
+}
+
+// onArchiveSession hides a session from the sidebar and search. Archived
+// sessions keep their messages and can be restored from the Archived view.
+func (ui *MainUI) onArchiveSession(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	if err := ui.storage.ArchiveSession(sessionID); err != nil {
+		dialog.ShowError(err, ui.window)
+		return
+	}
+	// A session may be archived while it generates in the background.
+	ui.agentService.CloseSession(sessionID)
+	if ui.currentSessionID == sessionID {
+		ui.currentSessionID = ""
+		ui.chatView.Clear()
+		ui.composer.SetUsage("")
+		ui.composer.SetStreaming(false)
+		ui.toolPanel.UpdateToolCalls(nil)
+	}
+	ui.sidebar.ClearSelection()
+	ui.sidebar.LoadSessions("default")
+}
+
+// showArchivedDialog lists archived sessions so they can be restored or
+// deleted permanently.
+func (ui *MainUI) showArchivedDialog() {
+	var sessions []models.Session
+	empty := widget.NewLabel("No archived chats.")
+
+	var list *widget.List
+	reload := func() {
+		var err error
+		sessions, err = ui.storage.ListArchivedSessions("default")
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		if len(sessions) == 0 {
+			empty.Show()
+		} else {
+			empty.Hide()
+		}
+		list.Refresh()
+	}
+
+	list = widget.NewList(
+		func() int { return len(sessions) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabelWithStyle("Session", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			title.Truncation = fyne.TextTruncateEllipsis
+			archived := widget.NewLabel("Archived")
+			restoreBtn := widget.NewButtonWithIcon("Restore", theme.ContentUndoIcon(), nil)
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			deleteBtn.Importance = widget.DangerImportance
+			return container.NewBorder(nil, nil, nil, container.NewHBox(restoreBtn, deleteBtn),
+				container.NewVBox(title, archived))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(sessions) {
+				return
+			}
+			session := sessions[id]
+			row := item.(*fyne.Container)
+			labels := row.Objects[0].(*fyne.Container)
+			buttons := row.Objects[1].(*fyne.Container)
+
+			labels.Objects[0].(*widget.Label).SetText(session.Title)
+			if session.ArchivedAt != nil {
+				labels.Objects[1].(*widget.Label).SetText("Archived " + session.ArchivedAt.Format("Jan 2, 2006 15:04"))
+			}
+
+			buttons.Objects[0].(*widget.Button).OnTapped = func() {
+				if err := ui.storage.UnarchiveSession(session.ID); err != nil {
+					dialog.ShowError(err, ui.window)
+					return
+				}
+				reload()
+				ui.sidebar.LoadSessions("default")
+			}
+			buttons.Objects[1].(*widget.Button).OnTapped = func() {
+				dialog.ShowConfirm(
+					"Delete Session",
+					fmt.Sprintf("Permanently delete %q and all its messages?", session.Title),
+					func(ok bool) {
+						if !ok {
+							return
+						}
+						if err := ui.deleteSession(session.ID); err != nil {
+							dialog.ShowError(err, ui.window)
+							return
+						}
+						reload()
+					},
+					ui.window,
+				)
+			}
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		list.Unselect(id)
+	}
+	reload()
+
+	d := dialog.NewCustom("Archived Chats", "Close", container.NewStack(list, container.NewCenter(empty)), ui.window)
+	d.Resize(fyne.NewSize(520, 420))
+	d.Show()
+}
+
 func (ui *MainUI) showSettingsDialog() {
 	provider := ui.config.GetActiveProvider()
+	if provider == nil {
+		dialog.ShowInformation("Settings", "No providers configured.", ui.window)
+		return
+	}
 
 	apiKeyEntry := widget.NewPasswordEntry()
 	apiKeyEntry.SetPlaceHolder("Enter API Key")
-	if provider != nil && provider.APIKey != "" {
-		apiKeyEntry.SetText(provider.APIKey)
-	}
+
+	baseURLEntry := widget.NewEntry()
+	baseURLEntry.SetPlaceHolder("Base URL (e.g. http://localhost:8080/v1)")
 
 	modelEntry := widget.NewEntry()
 	modelEntry.SetPlaceHolder("Model (e.g. gemini-1.5-flash)")
-	if provider != nil {
-		modelEntry.SetText(provider.Model)
+
+	contextEntry := widget.NewEntry()
+	contextEntry.SetPlaceHolder(fmt.Sprintf("Tokens (default %d)", models.DefaultContextWindow))
+
+	baseURLLabel := widget.NewLabel("Base URL")
+
+	loadProvider := func(p *models.Provider) {
+		provider = p
+		apiKeyEntry.SetText(p.APIKey)
+		baseURLEntry.SetText(p.BaseURL)
+		modelEntry.SetText(p.Model)
+		contextEntry.SetText("")
+		if p.ContextWindow > 0 {
+			contextEntry.SetText(strconv.Itoa(p.ContextWindow))
+		}
+		if p.Type == models.ProviderOpenAI {
+			baseURLLabel.Show()
+			baseURLEntry.Show()
+		} else {
+			baseURLLabel.Hide()
+			baseURLEntry.Hide()
+		}
+	}
+
+	names := make([]string, len(ui.config.Providers))
+	for i, p := range ui.config.Providers {
+		names[i] = p.Name
 	}
+	providerSelect := widget.NewSelect(names, func(name string) {
+		for i := range ui.config.Providers {
+			if ui.config.Providers[i].Name == name {
+				loadProvider(&ui.config.Providers[i])
+				return
+			}
+		}
+	})
+	providerSelect.SetSelected(provider.Name)
+	loadProvider(provider)
 
 	saveBtn := widget.NewButton("Save", func() {
-		if provider != nil {
-			provider.APIKey = apiKeyEntry.Text
-			provider.Model = modelEntry.Text
-			if err := ui.config.Save(); err != nil {
-				dialog.ShowError(err, ui.window)
+		contextWindow := 0
+		if text := strings.TrimSpace(contextEntry.Text); text != "" {
+			n, err := strconv.Atoi(text)
+			if err != nil || n < 0 {
+				dialog.ShowError(fmt.Errorf("context window must be a number of tokens"), ui.window)
 				return
 			}
-			ui.agentService.RemoveRunner(ui.currentSessionID)
-			dialog.ShowInformation("Settings Saved", "Provider settings updated.", ui.window)
+			contextWindow = n
 		}
+		provider.ContextWindow = contextWindow
+		provider.APIKey = apiKeyEntry.Text
+		provider.BaseURL = baseURLEntry.Text
+		provider.Model = modelEntry.Text
+		ui.config.ActiveProviderID = provider.ID
+		if err := ui.config.Save(); err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		ui.agentService.RemoveRunner(ui.currentSessionID)
+		dialog.ShowInformation("Settings Saved", "Provider settings updated.", ui.window)
 	})
 	saveBtn.Importance = widget.HighImportance
 
@@ -201,31 +582,139 @@ func (ui *MainUI) showSettingsDialog() {
 
 	content := container.NewVBox(
 		widget.NewLabelWithStyle("API Settings", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-		widget.NewLabel("Active Provider: "+provider.Name),
+		widget.NewLabel("Active Provider"),
+		providerSelect,
 		widget.NewLabel("API Key"),
 		apiKeyEntry,
+		baseURLLabel,
+		baseURLEntry,
 		widget.NewLabel("Model"),
 		modelEntry,
+		widget.NewLabel("Context Window"),
+		contextEntry,
 		container.NewHBox(layout.NewSpacer(), cancelBtn, saveBtn),
 	)
 
 	d := dialog.NewCustomWithoutButtons("", content, ui.window)
-	d.Resize(fyne.NewSize(500, 300))
+	d.Resize(fyne.NewSize(500, 440))
 
 	cancelBtn.OnTapped = func() { d.Hide() }
 
 	d.Show()
 }
 
-func (ui *MainUI) onSendMessage(content string) {
-	if ui.currentSessionID == "" {
+func (ui *MainUI) showSessionSettingsDialog(sessionID string) {
+	if sessionID == "" {
+		dialog.ShowInformation("Session Settings", "Select a session first.", ui.window)
+		return
+	}
+
+	session, err := ui.storage.GetSession(sessionID)
+	if err != nil {
+		dialog.ShowError(err, ui.window)
+		return
+	}
+
+	titleEntry := widget.NewEntry()
+	titleEntry.SetText(session.Title)
+
+	modelEntry := widget.NewEntry()
+	modelEntry.SetPlaceHolder("Model (defaults to the provider model)")
+	modelEntry.SetText(session.Model)
+
+	promptEntry := widget.NewMultiLineEntry()
+	promptEntry.Wrapping = fyne.TextWrapWord
+	promptEntry.SetMinRowsVisible(6)
+	promptEntry.SetText(session.SystemPrompt)
+
+	providerIDs := make([]string, len(ui.config.Providers))
+	names := make([]string, len(ui.config.Providers))
+	for i, p := range ui.config.Providers {
+		providerIDs[i] = p.ID
+		names[i] = p.Name
+	}
+	selectedProviderID := session.ProviderID
+	providerSelect := widget.NewSelect(names, func(name string) {
+		for i, n := range names {
+			if n != name {
+				continue
+			}
+			if providerIDs[i] != selectedProviderID {
+				modelEntry.SetText(ui.config.Providers[i].Model)
+			}
+			selectedProviderID = providerIDs[i]
+			return
+		}
+	})
+	if provider := ui.config.GetProvider(session.ProviderID); provider != nil {
+		providerSelect.SetSelected(provider.Name)
+	} else if provider := ui.config.GetActiveProvider(); provider != nil {
+		providerSelect.SetSelected(provider.Name)
+	}
+
+	workspaceField, workspaceRoot := ui.newWorkspaceField(session.Workspace)
+
+	saveBtn := widget.NewButton("Save", nil)
+	saveBtn.Importance = widget.HighImportance
+
+	cancelBtn := widget.NewButton("Cancel", nil)
+	cancelBtn.Importance = widget.LowImportance
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle("Session Settings", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel("Title"),
+		titleEntry,
+		widget.NewLabel("Provider"),
+		providerSelect,
+		widget.NewLabel("Model"),
+		modelEntry,
+		widget.NewLabel("System Prompt"),
+		promptEntry,
+		widget.NewLabel("Workspace Folder"),
+		workspaceField,
+		container.NewHBox(layout.NewSpacer(), cancelBtn, saveBtn),
+	)
+
+	d := dialog.NewCustomWithoutButtons("", content, ui.window)
+	d.Resize(fyne.NewSize(560, 580))
+
+	cancelBtn.OnTapped = func() { d.Hide() }
+
+	saveBtn.OnTapped = func() {
+		if titleEntry.Text != "" {
+			session.Title = titleEntry.Text
+		}
+		session.ProviderID = selectedProviderID
+		session.Model = modelEntry.Text
+		session.SystemPrompt = promptEntry.Text
+		session.Workspace = workspaceRoot()
+
+		if err := ui.storage.UpdateSession(session); err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		ui.agentService.RemoveRunner(session.ID)
+		ui.sidebar.UpdateSession(*session)
+		d.Hide()
+	}
+
+	d.Show()
+}
+
+func (ui *MainUI) onSendMessage(content string, attachments []models.Attachment) {
+	newSession := ui.currentSessionID == ""
+	if newSession {
+		title := content
+		if title == "" && len(attachments) > 0 {
+			title = attachment.Name(attachments[0])
+		}
 		provider := ui.config.GetActiveProvider()
 		session := &models.Session{
 			UserID:       "default",
-			Title:        content[:min(50, len(content))] + "...",
+			Title:        draftTitle(title),
 			Model:        provider.Model,
 			ProviderID:   provider.ID,
-			SystemPrompt: "You are a helpful AI assistant.",
+			SystemPrompt: models.DefaultSystemPrompt,
 		}
 
 		if err := ui.storage.CreateSession(session); err != nil {
@@ -235,56 +724,357 @@ func (ui *MainUI) onSendMessage(content string) {
 		ui.sidebar.AddSession(*session)
 		ui.currentSessionID = session.ID
 	}
+	sessionID := ui.currentSessionID
 
 	ui.chatView.AddMessage("user", content)
+	ui.chatView.SetLastAttachments(attachments)
 	ui.chatView.AddMessage("assistant", "")
-	ui.chatView.SetStatus("Thinking...")
+	ui.streams.Start(sessionID)
 	ui.composer.SetEnabled(false)
 
+	// Replies stream in on the agent's goroutine and are handed to the
+	// coordinator, which only draws them while their session is shown.
 	ctx := context.Background()
-	err := ui.agentService.SendMessage(ctx, ui.currentSessionID, content,
+	err := ui.agentService.SendMessage(ctx, sessionID, content, attachments,
 		func(role, content string) {
-			if role == "assistant" {
-				fyne.Do(func() {
-					if content != "" {
-						ui.chatView.ClearStatus()
-					}
-					ui.chatView.UpdateLastMessage(content)
-				})
-				return
-			}
-			if role == "system" {
-				fyne.Do(func() {
-					ui.chatView.ClearStatus()
-					ui.chatView.RemoveLastAssistantIfEmpty()
-					ui.chatView.AddMessage("system", content)
-				})
-			}
+			fyne.Do(func() {
+				switch role {
+				case "assistant":
+					ui.streams.Reply(sessionID, content)
+				case "system":
+					ui.streams.Error(sessionID, content)
+				}
+			})
 		},
-		func(toolName string, args, result map[string]any, err error) {
+		func(call models.ToolCall) {
 			fyne.Do(func() {
-				if toolName != "" {
-					if result != nil {
-						ui.chatView.AddNote("Tool done: " + toolName)
-						return
-					}
-					ui.chatView.AddNote("Tool: " + toolName)
+				if call.Error != nil || call.Result != nil {
+					ui.toolPanel.EndToolOutput(call.ID)
+				}
+				if ui.currentSessionID == sessionID {
+					ui.toolPanel.UpsertToolCall(call)
+				}
+				if call.ToolName == "" {
+					return
+				}
+				switch {
+				case call.Error != nil:
+					ui.streams.Note(sessionID, "Tool failed: "+call.ToolName)
+				case call.Result != nil:
+					ui.streams.Note(sessionID, "Tool done: "+call.ToolName)
+				default:
+					ui.streams.Note(sessionID, "Tool: "+call.ToolName)
 				}
 			})
 		},
-		nil,
+		ui.toolPanel.AppendDebug,
+		func(status models.MessageStatus) {
+			fyne.Do(func() {
+				visible := ui.streams.Finish(sessionID)
+				if status == models.StatusCompleted {
+					ui.describeSession(sessionID, newSession)
+				}
+				if !visible {
+					return
+				}
+				switch status {
+				case models.StatusCancelled:
+					ui.chatView.RemoveLastAssistantIfEmpty()
+					ui.chatView.AddNote("Generation stopped")
+				case models.StatusFailed:
+					ui.chatView.RemoveLastAssistantIfEmpty()
+					ui.addRetry("Response failed", content, attachments)
+				}
+				ui.updateUsage(sessionID)
+			})
+		},
 	)
 
 	if err != nil {
+		ui.streams.Finish(sessionID)
+		ui.chatView.RemoveLastAssistantIfEmpty()
 		ui.chatView.AddMessage("system", fmt.Sprintf("Error: %v", err))
 	}
 
 	ui.composer.SetEnabled(true)
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// addRetry shows a failure line whose Retry button sends prompt and its
+// attachments again.
+func (ui *MainUI) addRetry(text, prompt string, attachments []models.Attachment) {
+	if prompt == "" && len(attachments) == 0 {
+		ui.chatView.AddNote(text)
+		return
+	}
+	ui.chatView.AddRetry(text, func() {
+		if ui.streams.Active(ui.currentSessionID) {
+			return
+		}
+		// The files are shared with the failed message; new records are
+		// created for the retry.
+		resend := make([]models.Attachment, len(attachments))
+		for i, a := range attachments {
+			resend[i] = models.Attachment{Type: a.Type, Path: a.Path, Metadata: a.Metadata}
+		}
+		ui.onSendMessage(prompt, resend)
+	})
+}
+
+// newWorkspaceField shows a session's workspace folder with buttons to
+// choose or clear it. The returned func reads the current choice.
+func (ui *MainUI) newWorkspaceField(root string) (fyne.CanvasObject, func() string) {
+	label := widget.NewLabel("")
+	label.Truncation = fyne.TextTruncateEllipsis
+	clearBtn := widget.NewButton("Clear", nil)
+	set := func(path string) {
+		root = path
+		if path == "" {
+			label.SetText("None (file tools are off)")
+			clearBtn.Disable()
+		} else {
+			label.SetText(path)
+			clearBtn.Enable()
+		}
+	}
+	set(root)
+	clearBtn.OnTapped = func() { set("") }
+	chooseBtn := widget.NewButton("Choose...", func() { ui.chooseFolder(set) })
+
+	field := container.NewBorder(nil, nil, nil, container.NewHBox(clearBtn, chooseBtn), label)
+	return field, func() string { return root }
+}
+
+// chooseFolder picks a workspace folder for the file tools.
+func (ui *MainUI) chooseFolder(onChosen func(string)) {
+	d := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		if uri == nil {
+			return
+		}
+		ws, err := workspace.New(uri.Path())
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		onChosen(ws.Root())
+	}, ui.window)
+	d.Show()
+}
+
+// showAttachDialog picks a file to attach to the next message.
+func (ui *MainUI) showAttachDialog() {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(io.LimitReader(reader, attachment.MaxSize+1))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to read %s: %w", reader.URI().Name(), err), ui.window)
+			return
+		}
+		a, err := attachment.Save(ui.config.AttachmentDir, reader.URI().Name(), data)
+		if err != nil {
+			dialog.ShowError(err, ui.window)
+			return
+		}
+		ui.composer.AddAttachment(a)
+	}, ui.window)
+	d.Show()
+}
+
+// attachFile copies a dropped file into the attachment directory and queues
+// it for the next message.
+func (ui *MainUI) attachFile(path string) {
+	a, err := attachment.Import(ui.config.AttachmentDir, path)
+	if err != nil {
+		dialog.ShowError(err, ui.window)
+		return
+	}
+	ui.composer.AddAttachment(a)
+}
+
+// pasteImage attaches the clipboard image, if there is one, as a PNG. The
+// clipboard is read off the UI thread as it runs a platform tool.
+func (ui *MainUI) pasteImage() {
+	go func() {
+		data, err := clipboard.ReadImage()
+		if errors.Is(err, clipboard.ErrNoImage) {
+			return
+		}
+		var a models.Attachment
+		if err == nil {
+			name := "pasted-" + time.Now().Format("20060102-150405") + ".png"
+			a, err = attachment.Save(ui.config.AttachmentDir, name, data)
+		}
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to paste image: %w", err), ui.window)
+				return
+			}
+			ui.composer.AddAttachment(a)
+		})
+	}()
+}
+
+// deleteSession stops a session's generation and deletes the session and the
+// attachment files of its messages.
+func (ui *MainUI) deleteSession(sessionID string) error {
+	ui.agentService.CloseSession(sessionID)
+	attachments, err := ui.storage.ListAttachments(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := ui.storage.DeleteSession(sessionID); err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := attachment.Remove(a); err != nil {
+			fmt.Printf("Failed to remove attachment: %v\n", err)
+		}
+	}
+	return nil
+}
+
+func failureText(msg models.Message) string {
+	if errMsg, ok := msg.Metadata["error"].(string); ok && errMsg != "" {
+		return "Failed: " + errMsg
+	}
+	return "Response failed"
+}
+
+func (ui *MainUI) onStopGeneration() {
+	if ui.currentSessionID == "" {
+		return
+	}
+	ui.agentService.Cancel(ui.currentSessionID)
+}
+
+const (
+	// summaryMinMessages is how long a session must be before it is
+	// summarized; summaryInterval is how many new messages trigger a fresh
+	// summary.
+	summaryMinMessages = 4
+	summaryInterval    = 10
+
+	// untitledSession is the title of sessions the user did not name; the
+	// model is asked to name them after a reply.
+	untitledSession = "New Chat"
+)
+
+// describeSession asks the model in the background for a title after a new
+// session's first reply, and for a new summary as the session grows.
+func (ui *MainUI) describeSession(sessionID string, needsTitle bool) {
+	session, err := ui.storage.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+	count, err := ui.storage.CountMessages(sessionID)
+	if err != nil {
+		return
+	}
+
+	last, known := ui.summarizedAt[sessionID]
+	if !known && session.Summary != nil {
+		// Summarized in an earlier run; wait for another interval.
+		ui.summarizedAt[sessionID] = count
+		last = count
+	}
+	needsSummary := count >= summaryMinMessages &&
+		(session.Summary == nil || count-last >= summaryInterval)
+	if needsSummary {
+		ui.summarizedAt[sessionID] = count
+	}
+	needsTitle = needsTitle || session.Title == untitledSession
+	if !needsTitle && !needsSummary {
+		return
+	}
+
+	go func() {
+		ctx := context.Background()
+		if needsTitle {
+			if _, err := ui.agentService.GenerateTitle(ctx, sessionID); err != nil {
+				fmt.Printf("Failed to generate title: %v\n", err)
+			}
+		}
+		if needsSummary {
+			if _, err := ui.agentService.GenerateSummary(ctx, sessionID); err != nil {
+				fmt.Printf("Failed to generate summary: %v\n", err)
+			}
+		}
+		session, err := ui.storage.GetSession(sessionID)
+		if err != nil {
+			return
+		}
+		fyne.Do(func() {
+			ui.sidebar.UpdateSession(*session)
+		})
+	}()
+}
+
+// updateUsage shows the session's token totals and how full its context
+// window is under the composer.
+func (ui *MainUI) updateUsage(sessionID string) {
+	if sessionID == "" {
+		ui.composer.SetUsage("")
+		return
+	}
+	session, err := ui.storage.GetSession(sessionID)
+	if err != nil {
+		ui.composer.SetUsage("")
+		return
+	}
+	usage, err := ui.storage.SessionTokenUsage(sessionID)
+	if err != nil || usage.Total() == 0 {
+		ui.composer.SetUsage("")
+		return
+	}
+
+	limit := models.DefaultContextWindow
+	if provider := ui.config.GetProvider(session.ProviderID); provider != nil {
+		limit = provider.ContextLimit()
+	} else if provider := ui.config.GetActiveProvider(); provider != nil {
+		limit = provider.ContextLimit()
+	}
+
+	text := fmt.Sprintf("%s tokens (%s in, %s out) · context %d%% of %s",
+		formatTokens(usage.Total()), formatTokens(usage.PromptTokens), formatTokens(usage.OutputTokens),
+		usage.ContextTokens*100/limit, formatTokens(limit))
+	if session.SummaryUntil != nil {
+		text += " · earlier messages summarized"
+	}
+	ui.composer.SetUsage(text)
+}
+
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// draftTitle names a new session after the first line of its first message
+// until the model suggests a better title.
+func draftTitle(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	title := strings.Join(strings.Fields(line), " ")
+	if title == "" {
+		return untitledSession
+	}
+	if utf8.RuneCountInString(title) > 50 {
+		title = string([]rune(title)[:50]) + "..."
 	}
-	return b
+	return title
 }
@@ -0,0 +1,275 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+type mdBlockKind int
+
+const (
+	mdProse mdBlockKind = iota
+	mdCode
+	mdTable
+)
+
+// mdBlock is a top-level chunk of a Markdown document. Fenced code blocks and
+// tables are split out because Fyne's Markdown parser renders them poorly.
+type mdBlock struct {
+	kind mdBlockKind
+	text string
+	lang string
+}
+
+// splitMarkdown splits src into prose, fenced code and table blocks. An
+// unterminated fence runs to the end of the document, which keeps code blocks
+// stable while they are still being streamed.
+func splitMarkdown(src string) []mdBlock {
+	lines := strings.Split(src, "\n")
+	var blocks []mdBlock
+	var prose []string
+
+	flushProse := func() {
+		text := strings.Trim(strings.Join(prose, "\n"), "\n")
+		if text != "" {
+			blocks = append(blocks, mdBlock{kind: mdProse, text: text})
+		}
+		prose = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence := codeFence(trimmed); fence != "" && !strings.HasPrefix(line, "    ") {
+			flushProse()
+			lang := strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1]))
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) &&
+					strings.Trim(strings.TrimSpace(lines[i]), fence[:1]) == "" {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, mdBlock{kind: mdCode, text: strings.Join(code, "\n"), lang: lang})
+			continue
+		}
+
+		if isTableRow(trimmed) && i+1 < len(lines) && isTableDelimiter(strings.TrimSpace(lines[i+1])) {
+			flushProse()
+			rows := []string{trimmed}
+			for i += 2; i < len(lines) && isTableRow(strings.TrimSpace(lines[i])); i++ {
+				rows = append(rows, strings.TrimSpace(lines[i]))
+			}
+			i--
+			blocks = append(blocks, mdBlock{kind: mdTable, text: strings.Join(rows, "\n")})
+			continue
+		}
+
+		prose = append(prose, line)
+	}
+	flushProse()
+
+	return blocks
+}
+
+func codeFence(line string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			n := len(line) - len(strings.TrimLeft(line, marker[:1]))
+			return strings.Repeat(marker[:1], n)
+		}
+	}
+	return ""
+}
+
+func isTableRow(line string) bool {
+	return strings.HasPrefix(line, "|") && strings.Count(line, "|") >= 2
+}
+
+func isTableDelimiter(line string) bool {
+	if !isTableRow(line) {
+		return false
+	}
+	for _, cell := range tableCells(line) {
+		cell = strings.Trim(cell, ":")
+		if cell == "" || strings.Trim(cell, "-") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func tableCells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	cells := strings.Split(row, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// MarkdownView renders Markdown as a column of blocks. Updates only touch the
+// blocks that changed, and prose blocks keep their unchanged segments so Fyne
+// can reuse the cached visuals while a response streams in.
+type MarkdownView struct {
+	box    *fyne.Container
+	blocks []*renderedBlock
+	window fyne.Window
+}
+
+type renderedBlock struct {
+	block mdBlock
+	obj   fyne.CanvasObject
+	rich  *widget.RichText
+	code  *CodeBlock
+}
+
+func NewMarkdownView(content string, window fyne.Window) *MarkdownView {
+	mv := &MarkdownView{box: container.NewVBox(), window: window}
+	mv.SetMarkdown(content)
+	return mv
+}
+
+func (mv *MarkdownView) Container() fyne.CanvasObject {
+	return mv.box
+}
+
+// SetMarkdown replaces the rendered document with content.
+func (mv *MarkdownView) SetMarkdown(content string) {
+	blocks := splitMarkdown(content)
+	changed := len(blocks) != len(mv.blocks)
+
+	for i, block := range blocks {
+		if i < len(mv.blocks) {
+			existing := mv.blocks[i]
+			if existing.block == block {
+				continue
+			}
+			if existing.block.kind == block.kind && block.kind != mdTable {
+				existing.update(block)
+				continue
+			}
+			mv.blocks[i] = mv.newRenderedBlock(block)
+			changed = true
+			continue
+		}
+		mv.blocks = append(mv.blocks, mv.newRenderedBlock(block))
+	}
+	mv.blocks = mv.blocks[:len(blocks)]
+
+	if !changed {
+		return
+	}
+	objects := make([]fyne.CanvasObject, len(mv.blocks))
+	for i, b := range mv.blocks {
+		objects[i] = b.obj
+	}
+	mv.box.Objects = objects
+	mv.box.Refresh()
+}
+
+func (mv *MarkdownView) newRenderedBlock(block mdBlock) *renderedBlock {
+	rb := &renderedBlock{block: block}
+	switch block.kind {
+	case mdCode:
+		rb.code = NewCodeBlock(block.text, block.lang, mv.window)
+		rb.obj = rb.code.Container()
+	case mdTable:
+		rb.obj = newMarkdownTable(block.text)
+	default:
+		rb.rich = widget.NewRichText(parseProse(block.text)...)
+		rb.rich.Wrapping = fyne.TextWrapWord
+		rb.obj = rb.rich
+	}
+	return rb
+}
+
+func (rb *renderedBlock) update(block mdBlock) {
+	rb.block = block
+	switch block.kind {
+	case mdCode:
+		rb.code.SetCode(block.text, block.lang)
+	case mdProse:
+		rb.rich.Segments = mergeSegments(rb.rich.Segments, parseProse(block.text))
+		rb.rich.Refresh()
+	}
+}
+
+func parseProse(text string) []widget.RichTextSegment {
+	segments := widget.NewRichTextFromMarkdown(text).Segments
+	applyChatTextSize(segments)
+	return segments
+}
+
+// applyChatTextSize renders body text at the chat text size, leaving headings
+// and code at their own sizes.
+func applyChatTextSize(segments []widget.RichTextSegment) {
+	for _, seg := range segments {
+		switch s := seg.(type) {
+		case *widget.TextSegment:
+			if s.Style.SizeName == theme.SizeNameText && !s.Style.TextStyle.Monospace {
+				s.Style.SizeName = ChatTextSizeName
+			}
+		case *widget.ListSegment:
+			applyChatTextSize(s.Items)
+		case *widget.ParagraphSegment:
+			applyChatTextSize(s.Texts)
+		}
+	}
+}
+
+// mergeSegments keeps the leading segments of old that are unchanged in next,
+// so only the tail of a streaming block is re-rendered.
+func mergeSegments(old, next []widget.RichTextSegment) []widget.RichTextSegment {
+	for i := range next {
+		if i >= len(old) || !reflect.DeepEqual(old[i], next[i]) {
+			break
+		}
+		next[i] = old[i]
+	}
+	return next
+}
+
+func newMarkdownTable(text string) fyne.CanvasObject {
+	rows := strings.Split(text, "\n")
+	header := tableCells(rows[0])
+	cols := len(header)
+
+	var cells []fyne.CanvasObject
+	for r, row := range rows {
+		values := header
+		if r > 0 {
+			values = tableCells(row)
+		}
+		for c := 0; c < cols; c++ {
+			value := ""
+			if c < len(values) {
+				value = values[c]
+			}
+			seg := &widget.TextSegment{
+				Text:  value,
+				Style: widget.RichTextStyle{SizeName: ChatTextSizeName, Inline: true},
+			}
+			if r == 0 {
+				seg.Style.TextStyle = fyne.TextStyle{Bold: true}
+			}
+			cell := widget.NewRichText(seg)
+			cell.Wrapping = fyne.TextWrapWord
+			cells = append(cells, cell)
+		}
+	}
+
+	bg := canvas.NewRectangle(VercelBlack)
+	bg.CornerRadius = 6
+	bg.StrokeColor = VercelGray
+	bg.StrokeWidth = 1
+	return container.NewStack(bg, container.NewPadded(container.NewGridWithColumns(cols, cells...)))
+}
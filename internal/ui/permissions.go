@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"axe-desktop/internal/agent"
+	"axe-desktop/pkg/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// policyLabels are the choices shown for a tool's permission policy.
+var policyLabels = map[models.ToolPolicy]string{
+	models.ToolPolicyAllow: "Always allow",
+	models.ToolPolicyAsk:   "Ask",
+	models.ToolPolicyDeny:  "Deny",
+}
+
+var policyOrder = []models.ToolPolicy{models.ToolPolicyAllow, models.ToolPolicyAsk, models.ToolPolicyDeny}
+
+// approveToolCall asks the user whether a tool call may run. It is called on
+// the agent's goroutine and blocks until the dialog is answered or the
+// generation is stopped.
+func (ui *MainUI) approveToolCall(ctx context.Context, req agent.ApprovalRequest) (agent.Approval, error) {
+	answer := make(chan agent.Approval, 1)
+	var d dialog.Dialog
+	fyne.Do(func() {
+		d = ui.showApprovalDialog(req, func(a agent.Approval) { answer <- a })
+	})
+
+	select {
+	case a := <-answer:
+		return a, nil
+	case <-ctx.Done():
+		fyne.Do(func() {
+			if d != nil {
+				d.Hide()
+			}
+		})
+		return agent.Approval{}, ctx.Err()
+	}
+}
+
+func (ui *MainUI) showApprovalDialog(req agent.ApprovalRequest, onAnswer func(agent.Approval)) dialog.Dialog {
+	heading := widget.NewLabelWithStyle(fmt.Sprintf("Allow %s to run?", req.ToolName), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	// Approvals for a session in the background name the session.
+	who := "The assistant"
+	if req.SessionID != ui.currentSessionID {
+		if session, err := ui.storage.GetSession(req.SessionID); err == nil {
+			who = fmt.Sprintf("The assistant in %q", session.Title)
+		}
+	}
+	what := "wants to call this tool with these arguments:"
+	if req.Preview != "" {
+		what = "wants to make this change:"
+	}
+	intro := widget.NewLabel(who + " " + what)
+	intro.Wrapping = fyne.TextWrapWord
+
+	var args *widget.RichText
+	if req.Preview != "" {
+		args = newDiffView(req.Preview)
+	} else {
+		args = widget.NewRichText(&widget.TextSegment{
+			Text:  formatArgs(req.Args),
+			Style: widget.RichTextStyleCodeBlock,
+		})
+	}
+	args.Wrapping = fyne.TextWrapBreak
+	argsScroll := container.NewVScroll(args)
+	argsScroll.SetMinSize(fyne.NewSize(480, 180))
+
+	remember := widget.NewCheck("Remember for this session", nil)
+	if req.AlwaysAsk {
+		remember.Hide()
+	}
+
+	allowBtn := widget.NewButton("Allow", nil)
+	allowBtn.Importance = widget.HighImportance
+	denyBtn := widget.NewButton("Deny", nil)
+	denyBtn.Importance = widget.DangerImportance
+
+	content := container.NewBorder(
+		container.NewVBox(heading, intro),
+		container.NewVBox(remember, container.NewHBox(layout.NewSpacer(), denyBtn, allowBtn)),
+		nil, nil,
+		argsScroll,
+	)
+
+	d := dialog.NewCustomWithoutButtons("Tool Permission", content, ui.window)
+	answer := func(allow bool) {
+		d.Hide()
+		onAnswer(agent.Approval{Allow: allow, Remember: remember.Checked})
+	}
+	allowBtn.OnTapped = func() { answer(true) }
+	denyBtn.OnTapped = func() { answer(false) }
+	d.Resize(fyne.NewSize(560, 380))
+	d.Show()
+	return d
+}
+
+// newDiffView shows a unified diff with added and removed lines colored.
+func newDiffView(diff string) *widget.RichText {
+	var segments []widget.RichTextSegment
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		style := widget.RichTextStyleCodeBlock
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			style.TextStyle.Bold = true
+		case strings.HasPrefix(line, "+"):
+			style.ColorName = theme.ColorNameSuccess
+		case strings.HasPrefix(line, "-"):
+			style.ColorName = theme.ColorNameError
+		case strings.HasPrefix(line, "@@"):
+			style.ColorName = theme.ColorNamePrimary
+		}
+		if line == "" {
+			line = " "
+		}
+		segments = append(segments, &widget.TextSegment{Text: line, Style: style})
+	}
+	return widget.NewRichText(segments...)
+}
+
+func formatArgs(args map[string]any) string {
+	if len(args) == 0 {
+		return "(no arguments)"
+	}
+	data, err := json.MarshalIndent(args, "", "  ")
+	if err != nil {
+		return fmt.Sprint(args)
+	}
+	return string(data)
+}
+
+// showToolPermissionsDialog edits the permission policy of each tool. Tools
+// that have been called are listed, and others can be added by name.
+func (ui *MainUI) showToolPermissionsDialog() {
+	policies, err := ui.storage.GetToolPolicies()
+	if err != nil {
+		dialog.ShowError(err, ui.window)
+		return
+	}
+	used, err := ui.storage.ListToolNames()
+	if err != nil {
+		dialog.ShowError(err, ui.window)
+		return
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range used {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range policies {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var options []string
+	for _, p := range policyOrder {
+		options = append(options, policyLabels[p])
+	}
+	policyFor := func(name, label string) models.ToolPolicy {
+		for p, l := range policyLabels {
+			if l == label {
+				return p
+			}
+		}
+		return agent.DefaultPolicy(name)
+	}
+
+	empty := widget.NewLabel("No tools have been used yet.")
+	list := widget.NewList(
+		func() int { return len(names) },
+		func() fyne.CanvasObject {
+			name := widget.NewLabel("tool")
+			name.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, nil, widget.NewSelect(options, nil), name)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(names) {
+				return
+			}
+			name := names[id]
+			row := item.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(name)
+			sel := row.Objects[1].(*widget.Select)
+			sel.OnChanged = nil
+			policy, ok := policies[name]
+			if !ok {
+				policy = agent.DefaultPolicy(name)
+			}
+			sel.SetSelected(policyLabels[policy])
+			sel.OnChanged = func(label string) {
+				policies[name] = policyFor(name, label)
+			}
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		list.Unselect(id)
+	}
+	if len(names) > 0 {
+		empty.Hide()
+	}
+
+	addEntry := widget.NewEntry()
+	addEntry.SetPlaceHolder("Tool name")
+	addBtn := widget.NewButton("Add", func() {
+		name := strings.TrimSpace(addEntry.Text)
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+		sort.Strings(names)
+		addEntry.SetText("")
+		empty.Hide()
+		list.Refresh()
+	})
+
+	help := widget.NewLabel("Tools without a policy ask before running, except the file tools that only read. Denied tools never run, and run_command asks every time unless denied.")
+	help.Wrapping = fyne.TextWrapWord
+	help.Importance = widget.LowImportance
+
+	content := container.NewBorder(
+		help,
+		container.NewBorder(nil, nil, nil, addBtn, addEntry),
+		nil, nil,
+		container.NewStack(list, container.NewCenter(empty)),
+	)
+
+	d := dialog.NewCustomConfirm("Tool Permissions", "Save", "Cancel", content, func(save bool) {
+		if !save {
+			return
+		}
+		// Only policies that differ from the default are stored.
+		stored := make(map[string]models.ToolPolicy)
+		for name, policy := range policies {
+			if policy != agent.DefaultPolicy(name) {
+				stored[name] = policy
+			}
+		}
+		if err := ui.storage.SetToolPolicies(stored); err != nil {
+			dialog.ShowError(err, ui.window)
+		}
+	}, ui.window)
+	d.Resize(fyne.NewSize(520, 460))
+	d.Show()
+}
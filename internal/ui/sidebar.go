@@ -4,31 +4,56 @@ import (
 	"axe-desktop/internal/storage"
 	"axe-desktop/pkg/models"
 	"fmt"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
 type Sidebar struct {
-	storage      *storage.Storage
-	sessionList  *widget.List
-	sessions     []models.Session
-	onSelect     func(sessionID string)
-	onNewSession func()
-	onDelete     func(sessionID string)
-	container    *fyne.Container
-	selectedID   string
+	storage        *storage.Storage
+	sessionList    *widget.List
+	sessions       []models.Session
+	searchEntry    *widget.Entry
+	resultList     *widget.List
+	results        []models.SearchResult
+	onSelect       func(sessionID string)
+	onNewSession   func()
+	onDelete       func(sessionID string)
+	onArchive      func(sessionID string)
+	onShowArchived func()
+	onSettings     func(sessionID string)
+	onSearchResult func(result models.SearchResult)
+	contextMenu    func(sessionID string) *fyne.Menu
+	summaryLabel   *widget.Label
+	summaryCard    *fyne.Container
+	container      *fyne.Container
+	selectedID     string
+	// The header buttons act on the selected session.
+	deleteBtn   *widget.Button
+	archiveBtn  *widget.Button
+	settingsBtn *widget.Button
+	// generating marks sessions with a reply in progress.
+	generating map[string]bool
 }
 
-func NewSidebar(store *storage.Storage, onSelect func(sessionID string), onNew func(), onDelete func(sessionID string)) *Sidebar {
+func NewSidebar(store *storage.Storage, onSelect func(sessionID string), onNew func(), onDelete func(sessionID string),
+	onArchive func(sessionID string), onShowArchived func(), onSettings func(sessionID string),
+	onSearchResult func(result models.SearchResult)) *Sidebar {
 	s := &Sidebar{
-		storage:      store,
-		onSelect:     onSelect,
-		onNewSession: onNew,
-		onDelete:     onDelete,
+		storage:        store,
+		onSelect:       onSelect,
+		onNewSession:   onNew,
+		onDelete:       onDelete,
+		onArchive:      onArchive,
+		onShowArchived: onShowArchived,
+		onSettings:     onSettings,
+		onSearchResult: onSearchResult,
+		generating:     make(map[string]bool),
 	}
 	s.build()
 	return s
@@ -43,16 +68,34 @@ func (s *Sidebar) build() {
 	})
 	newBtn.Importance = widget.MediumImportance
 
-	deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+	s.deleteBtn = widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
 		if s.selectedID == "" {
 			return
 		}
 		s.onDelete(s.selectedID)
 	})
-	deleteBtn.Importance = widget.LowImportance
-	deleteBtn.Disable()
+	s.deleteBtn.Importance = widget.LowImportance
+	s.deleteBtn.Disable()
 
-	headerActions := container.NewHBox(deleteBtn, newBtn)
+	s.archiveBtn = widget.NewButtonWithIcon("", theme.StorageIcon(), func() {
+		if s.selectedID == "" {
+			return
+		}
+		s.onArchive(s.selectedID)
+	})
+	s.archiveBtn.Importance = widget.LowImportance
+	s.archiveBtn.Disable()
+
+	s.settingsBtn = widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
+		if s.selectedID == "" {
+			return
+		}
+		s.onSettings(s.selectedID)
+	})
+	s.settingsBtn.Importance = widget.LowImportance
+	s.settingsBtn.Disable()
+
+	headerActions := container.NewHBox(s.settingsBtn, s.archiveBtn, s.deleteBtn, newBtn)
 	header := container.NewBorder(nil, nil, nil, headerActions, title)
 
 	separator := canvas.NewRectangle(VercelGray)
@@ -61,19 +104,21 @@ func (s *Sidebar) build() {
 	s.sessionList = widget.NewList(
 		func() int { return len(s.sessions) },
 		func() fyne.CanvasObject {
-			icon := widget.NewIcon(theme.DocumentIcon())
-			label := widget.NewLabel("Session")
-			label.Truncation = fyne.TextTruncateEllipsis
-			return container.NewHBox(icon, label)
+			return newSessionRow(s.showContextMenu, s.showSummary)
 		},
 		func(id widget.ListItemID, item fyne.CanvasObject) {
 			if id >= len(s.sessions) {
 				return
 			}
 			session := s.sessions[id]
-			box := item.(*fyne.Container)
-			label := box.Objects[1].(*widget.Label)
-			label.SetText(session.Title)
+			row := item.(*sessionRow)
+			row.sessionID = session.ID
+			row.summary = ""
+			if session.Summary != nil {
+				row.summary = *session.Summary
+			}
+			row.label.SetText(session.Title)
+			row.setGenerating(s.generating[session.ID])
 		},
 	)
 
@@ -81,17 +126,144 @@ func (s *Sidebar) build() {
 		if id < len(s.sessions) {
 			s.onSelect(s.sessions[id].ID)
 			s.selectedID = s.sessions[id].ID
-			deleteBtn.Enable()
+			s.deleteBtn.Enable()
+			s.archiveBtn.Enable()
+			s.settingsBtn.Enable()
 		}
 	}
 
+	s.buildSearch()
+
+	archivedBtn := widget.NewButtonWithIcon("Archived", theme.FolderIcon(), func() {
+		s.onShowArchived()
+	})
+	archivedBtn.Importance = widget.LowImportance
+	archivedBtn.Alignment = widget.ButtonAlignLeading
+
+	s.summaryLabel = widget.NewLabel("")
+	s.summaryLabel.Wrapping = fyne.TextWrapWord
+	s.summaryLabel.Importance = widget.LowImportance
+	cardBg := canvas.NewRectangle(VercelGray)
+	cardBg.CornerRadius = 6
+	s.summaryCard = container.NewStack(cardBg, s.summaryLabel)
+	s.summaryCard.Hide()
+
 	s.container = container.NewBorder(
-		container.NewVBox(header, separator),
-		nil, nil, nil,
-		s.sessionList,
+		container.NewVBox(header, s.searchEntry, separator),
+		container.NewVBox(s.summaryCard, archivedBtn), nil, nil,
+		container.NewStack(s.sessionList, s.resultList),
 	)
 }
 
+func (s *Sidebar) buildSearch() {
+	s.searchEntry = widget.NewEntry()
+	s.searchEntry.SetPlaceHolder("Search chats...")
+	s.searchEntry.ActionItem = widget.NewButtonWithIcon("", theme.ContentClearIcon(), func() {
+		s.searchEntry.SetText("")
+	})
+	s.searchEntry.OnChanged = s.search
+
+	s.resultList = widget.NewList(
+		func() int { return len(s.results) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabelWithStyle("Session", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			title.Truncation = fyne.TextTruncateEllipsis
+			snippet := widget.NewRichText()
+			snippet.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(title, snippet)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(s.results) {
+				return
+			}
+			result := s.results[id]
+			box := item.(*fyne.Container)
+			box.Objects[0].(*widget.Label).SetText(result.SessionTitle)
+			snippet := box.Objects[1].(*widget.RichText)
+			snippet.Segments = snippetSegments(result.Snippet)
+			snippet.Refresh()
+		},
+	)
+	s.resultList.OnSelected = func(id widget.ListItemID) {
+		if id < len(s.results) {
+			s.onSearchResult(s.results[id])
+		}
+		// Allow jumping to the same result again.
+		s.resultList.Unselect(id)
+	}
+	s.resultList.Hide()
+}
+
+// snippetSegments shows a search snippet with the matched terms in bold.
+func snippetSegments(snippet string) []widget.RichTextSegment {
+	var segments []widget.RichTextSegment
+	for i, part := range strings.Split(snippet, storage.SnippetStart) {
+		plain := part
+		if i > 0 {
+			match, rest, _ := strings.Cut(part, storage.SnippetEnd)
+			segments = append(segments, &widget.TextSegment{Text: match, Style: widget.RichTextStyleStrong})
+			plain = rest
+		}
+		if plain != "" {
+			segments = append(segments, &widget.TextSegment{Text: plain, Style: widget.RichTextStyleInline})
+		}
+	}
+	return segments
+}
+
+func (s *Sidebar) search(query string) {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < 2 {
+		s.results = nil
+		s.resultList.Hide()
+		s.sessionList.Show()
+		return
+	}
+
+	results, err := s.storage.SearchMessages(query, 50)
+	if err != nil {
+		fmt.Printf("Failed to search messages: %v\n", err)
+		return
+	}
+	s.results = results
+	s.resultList.UnselectAll()
+	s.resultList.Refresh()
+	s.sessionList.Hide()
+	s.resultList.Show()
+}
+
+// SetContextMenu sets the builder for the menu shown when a session is
+// right-clicked.
+func (s *Sidebar) SetContextMenu(build func(sessionID string) *fyne.Menu) {
+	s.contextMenu = build
+}
+
+func (s *Sidebar) showContextMenu(sessionID string, e *fyne.PointEvent, row fyne.CanvasObject) {
+	if s.contextMenu == nil || sessionID == "" {
+		return
+	}
+	menu := s.contextMenu(sessionID)
+	if menu == nil {
+		return
+	}
+	canvas := fyne.CurrentApp().Driver().CanvasForObject(row)
+	if canvas == nil {
+		return
+	}
+	widget.ShowPopUpMenuAtPosition(menu, canvas, e.AbsolutePosition)
+}
+
+// showSummary shows the summary of the session under the pointer at the
+// bottom of the sidebar, or hides it when summary is empty.
+func (s *Sidebar) showSummary(summary string) {
+	if summary == "" {
+		s.summaryCard.Hide()
+		return
+	}
+	s.summaryLabel.SetText(summary)
+	s.summaryCard.Show()
+}
+
 func (s *Sidebar) Container() fyne.CanvasObject {
 	return s.container
 }
@@ -106,7 +278,110 @@ func (s *Sidebar) LoadSessions(userID string) {
 	s.sessionList.Refresh()
 }
 
+// SelectSession selects a session in the list, notifying onSelect even if it
+// is already selected.
+func (s *Sidebar) SelectSession(sessionID string) {
+	for i := range s.sessions {
+		if s.sessions[i].ID != sessionID {
+			continue
+		}
+		if s.selectedID == sessionID {
+			s.onSelect(sessionID)
+			return
+		}
+		s.sessionList.Select(i)
+		return
+	}
+}
+
+// ClearSelection unselects the selected session, for when it is closed or
+// leaves the list.
+func (s *Sidebar) ClearSelection() {
+	s.sessionList.UnselectAll()
+	s.selectedID = ""
+	s.deleteBtn.Disable()
+	s.archiveBtn.Disable()
+	s.settingsBtn.Disable()
+}
+
 func (s *Sidebar) AddSession(session models.Session) {
 	s.sessions = append([]models.Session{session}, s.sessions...)
 	s.sessionList.Refresh()
 }
+
+func (s *Sidebar) UpdateSession(session models.Session) {
+	for i := range s.sessions {
+		if s.sessions[i].ID == session.ID {
+			s.sessions[i] = session
+			break
+		}
+	}
+	s.sessionList.Refresh()
+}
+
+// SetGenerating shows or hides the spinner on a session's row.
+func (s *Sidebar) SetGenerating(sessionID string, generating bool) {
+	if generating {
+		s.generating[sessionID] = true
+	} else {
+		delete(s.generating, sessionID)
+	}
+	s.sessionList.Refresh()
+}
+
+// sessionRow is a session list item that opens a context menu on right-click
+// and reports its summary while the pointer is over it.
+type sessionRow struct {
+	widget.BaseWidget
+	sessionID     string
+	summary       string
+	label         *widget.Label
+	spinner       *widget.Activity
+	content       fyne.CanvasObject
+	onContextMenu func(sessionID string, e *fyne.PointEvent, row fyne.CanvasObject)
+	onHover       func(summary string)
+}
+
+func newSessionRow(onContextMenu func(sessionID string, e *fyne.PointEvent, row fyne.CanvasObject),
+	onHover func(summary string)) *sessionRow {
+	r := &sessionRow{onContextMenu: onContextMenu, onHover: onHover}
+	icon := widget.NewIcon(theme.DocumentIcon())
+	r.label = widget.NewLabel("Session")
+	r.label.Truncation = fyne.TextTruncateEllipsis
+	r.spinner = widget.NewActivity()
+	r.spinner.Hide()
+	r.content = container.NewBorder(nil, nil, icon, r.spinner, r.label)
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (r *sessionRow) setGenerating(generating bool) {
+	if generating == r.spinner.Visible() {
+		return
+	}
+	if generating {
+		r.spinner.Show()
+		r.spinner.Start()
+	} else {
+		r.spinner.Stop()
+		r.spinner.Hide()
+	}
+}
+
+func (r *sessionRow) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(r.content)
+}
+
+func (r *sessionRow) TappedSecondary(e *fyne.PointEvent) {
+	r.onContextMenu(r.sessionID, e, r)
+}
+
+func (r *sessionRow) MouseIn(*desktop.MouseEvent) {
+	r.onHover(r.summary)
+}
+
+func (r *sessionRow) MouseMoved(*desktop.MouseEvent) {}
+
+func (r *sessionRow) MouseOut() {
+	r.onHover("")
+}
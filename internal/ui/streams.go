@@ -0,0 +1,126 @@
+package ui
+
+// sessionStream is the live state of a reply being generated for a session,
+// kept so the session can be shown again mid-stream.
+type sessionStream struct {
+	reply string
+	// status is the status line shown until the first text arrives.
+	status string
+	// notes are the tool progress lines shown above the reply.
+	notes []string
+}
+
+// streamCoordinator owns the replies being generated across sessions. Several
+// sessions can generate at once; only the selected one is drawn in the chat
+// view and controls the composer, and the sidebar marks the others. All
+// methods run on the UI goroutine.
+type streamCoordinator struct {
+	streams  map[string]*sessionStream
+	current  func() string
+	chatView *ChatView
+	composer *Composer
+	sidebar  *Sidebar
+}
+
+func newStreamCoordinator(current func() string, chatView *ChatView, composer *Composer, sidebar *Sidebar) *streamCoordinator {
+	return &streamCoordinator{
+		streams:  make(map[string]*sessionStream),
+		current:  current,
+		chatView: chatView,
+		composer: composer,
+		sidebar:  sidebar,
+	}
+}
+
+func (sc *streamCoordinator) visible(sessionID string) bool {
+	return sessionID != "" && sc.current() == sessionID
+}
+
+// Active reports whether a reply is being generated for the session.
+func (sc *streamCoordinator) Active(sessionID string) bool {
+	_, ok := sc.streams[sessionID]
+	return ok
+}
+
+// Start begins tracking a reply. The caller has already added the empty
+// assistant bubble to the chat view.
+func (sc *streamCoordinator) Start(sessionID string) {
+	stream := &sessionStream{status: "Thinking..."}
+	sc.streams[sessionID] = stream
+	sc.sidebar.SetGenerating(sessionID, true)
+	if sc.visible(sessionID) {
+		sc.chatView.SetStatus(stream.status)
+		sc.composer.SetStreaming(true)
+	}
+}
+
+// Reply updates the reply text generated so far.
+func (sc *streamCoordinator) Reply(sessionID, content string) {
+	stream, ok := sc.streams[sessionID]
+	if !ok {
+		return
+	}
+	stream.reply = content
+	if content != "" {
+		stream.status = ""
+	}
+	if !sc.visible(sessionID) {
+		return
+	}
+	if content != "" {
+		sc.chatView.ClearStatus()
+	}
+	sc.chatView.UpdateLastMessage(content)
+}
+
+// Note adds a progress line, such as a tool call, above the reply.
+func (sc *streamCoordinator) Note(sessionID, text string) {
+	stream, ok := sc.streams[sessionID]
+	if !ok {
+		return
+	}
+	stream.notes = append(stream.notes, text)
+	if sc.visible(sessionID) {
+		sc.chatView.AddNote(text)
+	}
+}
+
+// Error shows an error reported during generation. Errors are saved with
+// the failed reply, so a session shown later reads them from history.
+func (sc *streamCoordinator) Error(sessionID, content string) {
+	if _, ok := sc.streams[sessionID]; !ok || !sc.visible(sessionID) {
+		return
+	}
+	sc.chatView.ClearStatus()
+	sc.chatView.RemoveLastAssistantIfEmpty()
+	sc.chatView.AddMessage("system", content)
+}
+
+// Finish stops tracking a reply and reports whether its session is shown.
+func (sc *streamCoordinator) Finish(sessionID string) bool {
+	delete(sc.streams, sessionID)
+	sc.sidebar.SetGenerating(sessionID, false)
+	if !sc.visible(sessionID) {
+		return false
+	}
+	sc.chatView.ClearStatus()
+	sc.composer.SetStreaming(false)
+	return true
+}
+
+// Show restores the live state of a session after its history was loaded
+// into the chat view, and sets the composer to match.
+func (sc *streamCoordinator) Show(sessionID string) {
+	stream, ok := sc.streams[sessionID]
+	sc.composer.SetStreaming(ok)
+	if !ok {
+		return
+	}
+	sc.chatView.AddMessage("assistant", stream.reply)
+	for _, note := range stream.notes {
+		sc.chatView.AddNote(note)
+	}
+	if stream.status != "" {
+		sc.chatView.SetStatus(stream.status)
+	}
+}
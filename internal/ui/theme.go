@@ -16,6 +16,15 @@ const (
 	ChatMetaSizeName fyne.ThemeSizeName = "chatMeta"
 )
 
+// Syntax highlighting colors for code blocks.
+const (
+	CodeKeywordColorName fyne.ThemeColorName = "codeKeyword"
+	CodeTypeColorName    fyne.ThemeColorName = "codeType"
+	CodeStringColorName  fyne.ThemeColorName = "codeString"
+	CodeNumberColorName  fyne.ThemeColorName = "codeNumber"
+	CodeCommentColorName fyne.ThemeColorName = "codeComment"
+)
+
 func NewVercelTheme() fyne.Theme {
 	return &VercelTheme{
 		defaultTheme: theme.DarkTheme(),
@@ -54,6 +63,16 @@ func (v *VercelTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant)
 		return color.RGBA{51, 51, 51, 200}
 	case theme.ColorNameSeparator:
 		return color.RGBA{15, 15, 15, 255}
+	case CodeKeywordColorName:
+		return VercelBlue
+	case CodeTypeColorName:
+		return VercelPurple
+	case CodeStringColorName:
+		return VercelSuccess
+	case CodeNumberColorName:
+		return VercelWarning
+	case CodeCommentColorName:
+		return VercelMuted
 
 	default:
 		return v.defaultTheme.Color(name, variant)
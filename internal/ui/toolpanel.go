@@ -4,9 +4,12 @@ import (
 	"axe-desktop/pkg/models"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -16,12 +19,28 @@ type ToolPanel struct {
 	toolList  *widget.List
 	toolCalls []models.ToolCall
 	debugLog  *widget.Entry
+
+	detail       *fyne.Container
+	detailTitle  *widget.Label
+	detailMeta   *widget.Label
+	detailArgs   *widget.Label
+	detailResult *widget.Label
+	detailError  *widget.Label
+	errorHeader  *widget.Label
+	detailOutput *widget.Label
+	outputHeader *widget.Label
+	selectedID   string
+
+	// outputs holds the output of running commands by tool call ID, until
+	// the call finishes and its result includes it.
+	outputs map[string]string
 }
 
 // NewToolPanel creates a new tool panel
 func NewToolPanel() *ToolPanel {
 	tp := &ToolPanel{
 		toolCalls: []models.ToolCall{},
+		outputs:   make(map[string]string),
 	}
 
 	tp.debugLog = widget.NewMultiLineEntry()
@@ -31,27 +50,39 @@ func NewToolPanel() *ToolPanel {
 	tp.toolList = widget.NewList(
 		func() int { return len(tp.toolCalls) },
 		func() fyne.CanvasObject {
-			return widget.NewLabel("Tool Call")
+			icon := widget.NewIcon(theme.ViewRefreshIcon())
+			name := widget.NewLabel("Tool Call")
+			name.Truncation = fyne.TextTruncateEllipsis
+			duration := widget.NewLabel("")
+			duration.TextStyle = fyne.TextStyle{Monospace: true}
+			return container.NewBorder(nil, nil, icon, duration, name)
 		},
 		func(id widget.ListItemID, item fyne.CanvasObject) {
 			if id >= len(tp.toolCalls) {
 				return
 			}
 			call := tp.toolCalls[id]
-			label := item.(*widget.Label)
-			label.SetText(fmt.Sprintf("%s: %s", call.ToolName, formatArgs(call.Args)))
+			box := item.(*fyne.Container)
+			box.Objects[0].(*widget.Label).SetText(call.ToolName)
+			box.Objects[1].(*widget.Icon).SetResource(toolCallIcon(call))
+			box.Objects[2].(*widget.Label).SetText(formatDuration(call.DurationMs))
 		},
 	)
 
+	tp.buildDetail()
+
 	tp.toolList.OnSelected = func(id widget.ListItemID) {
 		if id < len(tp.toolCalls) {
-			// Show details in a dialog
-			// TODO: Implement detail view
+			tp.selectedID = tp.toolCalls[id].ID
+			tp.showDetail(tp.toolCalls[id])
 		}
 	}
 
+	calls := container.NewVSplit(tp.toolList, container.NewVScroll(tp.detail))
+	calls.SetOffset(0.4)
+
 	tp.tabs = container.NewAppTabs(
-		container.NewTabItem("Tool Calls", tp.toolList),
+		container.NewTabItem("Tool Calls", calls),
 		container.NewTabItem("Reasoning", widget.NewLabel("Reasoning summary will appear here")),
 		container.NewTabItem("Debug", tp.debugLog),
 	)
@@ -59,6 +90,108 @@ func NewToolPanel() *ToolPanel {
 	return tp
 }
 
+func (tp *ToolPanel) buildDetail() {
+	tp.detailTitle = widget.NewLabelWithStyle("Select a tool call", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	tp.detailMeta = widget.NewLabel("")
+	tp.detailMeta.Wrapping = fyne.TextWrapWord
+
+	newCodeLabel := func() *widget.Label {
+		l := widget.NewLabel("")
+		l.TextStyle = fyne.TextStyle{Monospace: true}
+		l.Wrapping = fyne.TextWrapWord
+		l.Selectable = true
+		return l
+	}
+	tp.detailArgs = newCodeLabel()
+	tp.detailResult = newCodeLabel()
+	tp.detailError = newCodeLabel()
+	tp.detailError.Importance = widget.DangerImportance
+	tp.detailOutput = newCodeLabel()
+
+	tp.errorHeader = sectionHeader("Error")
+	tp.outputHeader = sectionHeader("Output")
+
+	tp.detail = container.NewVBox(
+		tp.detailTitle,
+		tp.detailMeta,
+		sectionHeader("Arguments"),
+		tp.detailArgs,
+		tp.outputHeader,
+		tp.detailOutput,
+		sectionHeader("Result"),
+		tp.detailResult,
+		tp.errorHeader,
+		tp.detailError,
+		layout.NewSpacer(),
+	)
+	tp.errorHeader.Hide()
+	tp.detailError.Hide()
+	tp.outputHeader.Hide()
+	tp.detailOutput.Hide()
+}
+
+func sectionHeader(text string) *widget.Label {
+	return widget.NewLabelWithStyle(text, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+}
+
+func (tp *ToolPanel) showDetail(call models.ToolCall) {
+	tp.detailTitle.SetText(call.ToolName)
+
+	status := "Running"
+	if call.Error != nil {
+		status = "Failed"
+	} else if call.Result != nil {
+		status = "Completed"
+	}
+	meta := fmt.Sprintf("Status: %s\nStarted: %s", status, call.CreatedAt.Format(time.DateTime))
+	if call.DurationMs != nil {
+		meta += "\nDuration: " + formatDuration(call.DurationMs)
+	}
+	tp.detailMeta.SetText(meta)
+
+	tp.detailArgs.SetText(prettyJSON(call.Args))
+	tp.showOutput(call.ID)
+	if call.Result != nil {
+		tp.detailResult.SetText(prettyJSON(call.Result))
+	} else {
+		tp.detailResult.SetText("—")
+	}
+
+	if call.Error != nil {
+		tp.detailError.SetText(*call.Error)
+		tp.errorHeader.Show()
+		tp.detailError.Show()
+	} else {
+		tp.errorHeader.Hide()
+		tp.detailError.Hide()
+	}
+}
+
+func (tp *ToolPanel) clearDetail() {
+	tp.selectedID = ""
+	tp.detailTitle.SetText("Select a tool call")
+	tp.detailMeta.SetText("")
+	tp.detailArgs.SetText("")
+	tp.detailResult.SetText("")
+	tp.errorHeader.Hide()
+	tp.detailError.Hide()
+	tp.outputHeader.Hide()
+	tp.detailOutput.Hide()
+}
+
+// showOutput shows the live output of a running call, if it has any.
+func (tp *ToolPanel) showOutput(callID string) {
+	output, ok := tp.outputs[callID]
+	if !ok {
+		tp.outputHeader.Hide()
+		tp.detailOutput.Hide()
+		return
+	}
+	tp.detailOutput.SetText(output)
+	tp.outputHeader.Show()
+	tp.detailOutput.Show()
+}
+
 // Container returns the tool panel container
 func (tp *ToolPanel) Container() fyne.CanvasObject {
 	return tp.tabs
@@ -81,10 +214,87 @@ func (tp *ToolPanel) AppendDebug(line string) {
 // UpdateToolCalls updates the displayed tool calls
 func (tp *ToolPanel) UpdateToolCalls(calls []models.ToolCall) {
 	tp.toolCalls = calls
+	tp.toolList.UnselectAll()
+	tp.clearDetail()
+	tp.toolList.Refresh()
+}
+
+// UpsertToolCall adds a live tool call or updates it once its result arrives.
+func (tp *ToolPanel) UpsertToolCall(call models.ToolCall) {
+	for i := range tp.toolCalls {
+		if tp.toolCalls[i].ID == call.ID {
+			tp.toolCalls[i] = call
+			tp.toolList.RefreshItem(i)
+			if tp.selectedID == call.ID {
+				tp.showDetail(call)
+			}
+			return
+		}
+	}
+
+	tp.toolCalls = append([]models.ToolCall{call}, tp.toolCalls...)
 	tp.toolList.Refresh()
+	if tp.selectedID != "" {
+		for i := range tp.toolCalls {
+			if tp.toolCalls[i].ID == tp.selectedID {
+				tp.toolList.Select(i)
+				break
+			}
+		}
+	}
+}
+
+// AppendToolOutput adds output from a running tool call. The call is selected
+// when nothing else is, so the output can be followed as it arrives.
+func (tp *ToolPanel) AppendToolOutput(callID, output string) {
+	tp.outputs[callID] += output
+	if tp.selectedID == callID {
+		tp.showOutput(callID)
+		return
+	}
+	if tp.selectedID != "" {
+		return
+	}
+	for i := range tp.toolCalls {
+		if tp.toolCalls[i].ID == callID {
+			tp.toolList.Select(i)
+			return
+		}
+	}
+}
+
+// EndToolOutput forgets the live output of a finished call.
+func (tp *ToolPanel) EndToolOutput(callID string) {
+	delete(tp.outputs, callID)
 }
 
-func formatArgs(args map[string]any) string {
-	data, _ := json.Marshal(args)
+func toolCallIcon(call models.ToolCall) fyne.Resource {
+	if call.Error != nil {
+		return theme.ErrorIcon()
+	}
+	if call.Result != nil {
+		return theme.ConfirmIcon()
+	}
+	return theme.ViewRefreshIcon()
+}
+
+func formatDuration(ms *int64) string {
+	if ms == nil {
+		return ""
+	}
+	if *ms < 1000 {
+		return fmt.Sprintf("%dms", *ms)
+	}
+	return fmt.Sprintf("%.1fs", float64(*ms)/1000)
+}
+
+func prettyJSON(v map[string]any) string {
+	if len(v) == 0 {
+		return "{}"
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
 	return string(data)
 }
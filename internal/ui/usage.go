@@ -0,0 +1,514 @@
+package ui
+
+import (
+	"axe-desktop/internal/config"
+	"axe-desktop/internal/storage"
+	"axe-desktop/internal/usage"
+	"axe-desktop/pkg/models"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// usageRanges are the periods the Usage window can show, in days; zero is
+// all time.
+var usageRanges = []struct {
+	label string
+	days  int
+}{
+	{"Last 7 days", 7},
+	{"Last 30 days", 30},
+	{"Last 90 days", 90},
+	{"All time", 0},
+}
+
+// UsageWindow shows token usage and estimated cost with a daily chart and
+// breakdowns by model, provider, session and day.
+type UsageWindow struct {
+	window  fyne.Window
+	storage *storage.Storage
+	config  *config.Config
+	report  *usage.Report
+	days    int
+
+	totalLabel  *widget.Label
+	inputLabel  *widget.Label
+	outputLabel *widget.Label
+	costLabel   *widget.Label
+	chart       *BarChart
+
+	byModel    []usage.Totals
+	byProvider []usage.Totals
+	bySession  []usage.Totals
+	byDay      []usage.Totals
+	tables     []*widget.Table
+}
+
+func NewUsageWindow(store *storage.Storage, cfg *config.Config) *UsageWindow {
+	u := &UsageWindow{
+		window:  fyne.CurrentApp().NewWindow("Usage"),
+		storage: store,
+		config:  cfg,
+		days:    30,
+	}
+	u.build()
+	u.window.Resize(fyne.NewSize(860, 640))
+	return u
+}
+
+func (u *UsageWindow) build() {
+	labels := make([]string, len(usageRanges))
+	for i, r := range usageRanges {
+		labels[i] = r.label
+	}
+	rangeSelect := widget.NewSelect(labels, func(label string) {
+		for _, r := range usageRanges {
+			if r.label == label {
+				u.days = r.days
+			}
+		}
+		u.Refresh()
+	})
+
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), u.Refresh)
+	refreshBtn.Importance = widget.LowImportance
+	pricesBtn := widget.NewButton("Prices...", u.showPrices)
+	exportBtn := widget.NewButtonWithIcon("Export CSV...", theme.DocumentSaveIcon(), u.exportCSV)
+
+	toolbar := container.NewHBox(rangeSelect, refreshBtn, layout.NewSpacer(), pricesBtn, exportBtn)
+
+	u.totalLabel = newStatValue()
+	u.inputLabel = newStatValue()
+	u.outputLabel = newStatValue()
+	u.costLabel = newStatValue()
+	stats := container.NewGridWithColumns(4,
+		newStatCard("Total tokens", u.totalLabel),
+		newStatCard("Input", u.inputLabel),
+		newStatCard("Output", u.outputLabel),
+		newStatCard("Estimated cost", u.costLabel),
+	)
+
+	u.chart = NewBarChart()
+	chartTitle := widget.NewLabelWithStyle("Tokens per day", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("By Model", u.newTotalsTable("Model", &u.byModel)),
+		container.NewTabItem("By Provider", u.newTotalsTable("Provider", &u.byProvider)),
+		container.NewTabItem("By Session", u.newTotalsTable("Session", &u.bySession)),
+		container.NewTabItem("By Day", u.newTotalsTable("Day", &u.byDay)),
+	)
+
+	top := container.NewVBox(toolbar, stats, chartTitle, u.chart)
+	u.window.SetContent(container.NewPadded(container.NewBorder(top, nil, nil, nil, tabs)))
+
+	rangeSelect.SetSelected(usageRanges[1].label)
+}
+
+func newStatValue() *widget.Label {
+	return widget.NewLabelWithStyle("-", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+}
+
+func newStatCard(title string, value *widget.Label) fyne.CanvasObject {
+	bg := canvas.NewRectangle(VercelDarkGray)
+	bg.CornerRadius = 8
+	bg.StrokeColor = VercelGray
+	bg.StrokeWidth = 1
+	caption := widget.NewLabel(title)
+	caption.Importance = widget.LowImportance
+	return container.NewStack(bg, container.NewPadded(container.NewVBox(caption, value)))
+}
+
+// newTotalsTable shows one breakdown of the report; rows points at the field
+// that holds it so the table follows refreshes.
+func (u *UsageWindow) newTotalsTable(name string, rows *[]usage.Totals) fyne.CanvasObject {
+	headers := []string{name, "Replies", "Input", "Output", "Total", "Cost"}
+
+	table := widget.NewTableWithHeaders(
+		func() (int, int) { return len(*rows), len(headers) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if id.Row >= len(*rows) {
+				label.SetText("")
+				return
+			}
+			t := (*rows)[id.Row]
+			label.Alignment = fyne.TextAlignTrailing
+			switch id.Col {
+			case 0:
+				label.Alignment = fyne.TextAlignLeading
+				label.SetText(t.Label)
+			case 1:
+				label.SetText(strconv.Itoa(t.Replies))
+			case 2:
+				label.SetText(formatTokens(t.PromptTokens))
+			case 3:
+				label.SetText(formatTokens(t.OutputTokens))
+			case 4:
+				label.SetText(formatTokens(t.Tokens()))
+			case 5:
+				label.SetText(costText(t))
+			}
+		},
+	)
+	table.ShowHeaderColumn = false
+	table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	table.UpdateHeader = func(id widget.TableCellID, cell fyne.CanvasObject) {
+		if id.Col >= 0 && id.Col < len(headers) {
+			cell.(*widget.Label).SetText(headers[id.Col])
+		}
+	}
+	table.SetColumnWidth(0, 300)
+	for col := 1; col < len(headers); col++ {
+		table.SetColumnWidth(col, 96)
+	}
+	u.tables = append(u.tables, table)
+	return table
+}
+
+func costText(t usage.Totals) string {
+	switch {
+	case t.Unpriced == 0:
+		return usage.FormatCost(t.Cost)
+	case t.Cost == 0:
+		return "-"
+	default:
+		return usage.FormatCost(t.Cost) + "+"
+	}
+}
+
+// Refresh reloads the usage for the selected period.
+func (u *UsageWindow) Refresh() {
+	var since time.Time
+	if u.days > 0 {
+		now := time.Now()
+		since = time.Date(now.Year(), now.Month(), now.Day()-u.days+1, 0, 0, 0, 0, time.Local)
+	}
+
+	report, err := usage.Load(u.storage, since, u.config.Prices, u.providerName)
+	if err != nil {
+		dialog.ShowError(err, u.window)
+		return
+	}
+	u.report = report
+
+	u.totalLabel.SetText(formatTokens(report.Total.Tokens()))
+	u.inputLabel.SetText(formatTokens(report.Total.PromptTokens))
+	u.outputLabel.SetText(formatTokens(report.Total.OutputTokens))
+	cost := usage.FormatCost(report.Total.Cost)
+	if report.Total.Unpriced > 0 {
+		cost += fmt.Sprintf(" (%s tokens unpriced)", formatTokens(report.Total.Unpriced))
+	}
+	u.costLabel.SetText(cost)
+
+	values := make([]float64, len(report.ByDay))
+	dayLabels := make([]string, len(report.ByDay))
+	for i, day := range report.ByDay {
+		values[i] = float64(day.Tokens())
+		dayLabels[i] = day.Label
+	}
+	u.chart.SetData(values, dayLabels)
+
+	u.byModel = report.ByModel
+	u.byProvider = report.ByProvider
+	u.bySession = report.BySession
+	// The day table lists the most recent day first.
+	u.byDay = u.byDay[:0]
+	for i := len(report.ByDay) - 1; i >= 0; i-- {
+		u.byDay = append(u.byDay, report.ByDay[i])
+	}
+	for _, table := range u.tables {
+		table.Refresh()
+	}
+}
+
+func (u *UsageWindow) providerName(id string) string {
+	if p := u.config.GetProvider(id); p != nil {
+		return p.Name
+	}
+	if id == "" {
+		return "(unknown)"
+	}
+	return id
+}
+
+func (u *UsageWindow) exportCSV() {
+	if u.report == nil {
+		return
+	}
+	report := u.report
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, u.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if err := usage.WriteCSV(writer, report, u.providerName); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to export usage: %w", err), u.window)
+		}
+	}, u.window)
+	d.SetFileName("axe-usage-" + time.Now().Format(time.DateOnly) + ".csv")
+	d.Show()
+}
+
+// showPrices edits the price table. Models seen in the report are listed so
+// their prices can be filled in; prices left empty are removed.
+func (u *UsageWindow) showPrices() {
+	type priceRow struct {
+		model  *widget.Entry
+		input  *widget.Entry
+		output *widget.Entry
+	}
+	var rows []priceRow
+	grid := container.NewGridWithColumns(3,
+		widget.NewLabelWithStyle("Model", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Input $ / 1M", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Output $ / 1M", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	)
+
+	addRow := func(price models.ModelPrice) {
+		row := priceRow{model: widget.NewEntry(), input: widget.NewEntry(), output: widget.NewEntry()}
+		row.model.SetPlaceHolder("Model or prefix")
+		row.model.SetText(price.Model)
+		if price.InputPerMillion > 0 || price.OutputPerMillion > 0 {
+			row.input.SetText(strconv.FormatFloat(price.InputPerMillion, 'f', -1, 64))
+			row.output.SetText(strconv.FormatFloat(price.OutputPerMillion, 'f', -1, 64))
+		}
+		rows = append(rows, row)
+		grid.Add(row.model)
+		grid.Add(row.input)
+		grid.Add(row.output)
+	}
+
+	listed := map[string]bool{}
+	for _, p := range u.config.Prices {
+		addRow(p)
+		listed[p.Model] = true
+	}
+	if u.report != nil {
+		for _, model := range u.report.Models() {
+			if _, ok := usage.PriceTable(u.config.Prices).Lookup(model); !ok && !listed[model] {
+				addRow(models.ModelPrice{Model: model})
+			}
+		}
+	}
+	if len(rows) == 0 {
+		addRow(models.ModelPrice{})
+	}
+
+	addBtn := widget.NewButtonWithIcon("Add Model", theme.ContentAddIcon(), func() {
+		addRow(models.ModelPrice{})
+	})
+	addBtn.Importance = widget.LowImportance
+
+	note := widget.NewLabel("Prices are in US dollars per million tokens. A model name also matches longer names that start with it.")
+	note.Wrapping = fyne.TextWrapWord
+	note.Importance = widget.LowImportance
+
+	content := container.NewBorder(note, container.NewHBox(addBtn), nil, nil, container.NewVScroll(grid))
+	d := dialog.NewCustomConfirm("Prices", "Save", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		var prices []models.ModelPrice
+		for _, row := range rows {
+			model := strings.TrimSpace(row.model.Text)
+			if model == "" || (strings.TrimSpace(row.input.Text) == "" && strings.TrimSpace(row.output.Text) == "") {
+				continue
+			}
+			input, err1 := parsePrice(row.input.Text)
+			output, err2 := parsePrice(row.output.Text)
+			if err1 != nil || err2 != nil {
+				dialog.ShowError(fmt.Errorf("invalid price for %s", model), u.window)
+				return
+			}
+			prices = append(prices, models.ModelPrice{Model: model, InputPerMillion: input, OutputPerMillion: output})
+		}
+		u.config.Prices = prices
+		if err := u.config.Save(); err != nil {
+			dialog.ShowError(err, u.window)
+			return
+		}
+		u.Refresh()
+	}, u.window)
+	d.Resize(fyne.NewSize(620, 460))
+	d.Show()
+}
+
+func parsePrice(text string) (float64, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "$")
+	if text == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid price: %q", text)
+	}
+	return v, nil
+}
+
+func (u *UsageWindow) Show() {
+	u.window.Show()
+	u.window.RequestFocus()
+}
+
+func (u *UsageWindow) SetOnClosed(fn func()) {
+	u.window.SetOnClosed(fn)
+}
+
+// BarChart draws a series of values as vertical bars with the largest value
+// and the first and last labels as axis annotations.
+type BarChart struct {
+	widget.BaseWidget
+	values []float64
+	labels []string
+}
+
+func NewBarChart() *BarChart {
+	c := &BarChart{}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *BarChart) SetData(values []float64, labels []string) {
+	c.values = values
+	c.labels = labels
+	c.Refresh()
+}
+
+func (c *BarChart) CreateRenderer() fyne.WidgetRenderer {
+	r := &barChartRenderer{
+		chart:    c,
+		baseline: canvas.NewRectangle(VercelGray),
+		maxText:  canvas.NewText("", VercelMuted),
+		first:    canvas.NewText("", VercelMuted),
+		last:     canvas.NewText("", VercelMuted),
+		empty:    canvas.NewText("No usage recorded", VercelMuted),
+	}
+	for _, t := range []*canvas.Text{r.maxText, r.first, r.last, r.empty} {
+		t.TextSize = theme.CaptionTextSize()
+	}
+	r.Refresh()
+	return r
+}
+
+type barChartRenderer struct {
+	chart    *BarChart
+	bars     []*canvas.Rectangle
+	baseline *canvas.Rectangle
+	maxText  *canvas.Text
+	first    *canvas.Text
+	last     *canvas.Text
+	empty    *canvas.Text
+}
+
+const (
+	chartHeight    = 160
+	chartAxisSpace = 18
+)
+
+func (r *barChartRenderer) peak() float64 {
+	var peak float64
+	for _, v := range r.chart.values {
+		if v > peak {
+			peak = v
+		}
+	}
+	return peak
+}
+
+func (r *barChartRenderer) Layout(size fyne.Size) {
+	plotTop := float32(chartAxisSpace)
+	plotHeight := size.Height - 2*chartAxisSpace
+	baselineY := plotTop + plotHeight
+
+	r.baseline.Move(fyne.NewPos(0, baselineY))
+	r.baseline.Resize(fyne.NewSize(size.Width, 1))
+
+	r.maxText.Move(fyne.NewPos(0, 0))
+	r.empty.Move(fyne.NewPos((size.Width-r.empty.MinSize().Width)/2, plotTop+plotHeight/2))
+
+	n := len(r.bars)
+	if n == 0 {
+		return
+	}
+	slot := size.Width / float32(n)
+	gap := slot * 0.2
+	if gap > 6 {
+		gap = 6
+	}
+	peak := r.peak()
+	for i, bar := range r.bars {
+		h := float32(0)
+		if peak > 0 {
+			h = float32(r.chart.values[i]/peak) * plotHeight
+		}
+		bar.Move(fyne.NewPos(float32(i)*slot+gap/2, baselineY-h))
+		bar.Resize(fyne.NewSize(slot-gap, h))
+	}
+
+	r.first.Move(fyne.NewPos(0, baselineY+2))
+	r.last.Move(fyne.NewPos(size.Width-r.last.MinSize().Width, baselineY+2))
+}
+
+func (r *barChartRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(200, chartHeight)
+}
+
+func (r *barChartRenderer) Refresh() {
+	values := r.chart.values
+	for len(r.bars) < len(values) {
+		r.bars = append(r.bars, canvas.NewRectangle(color.Transparent))
+	}
+	r.bars = r.bars[:len(values)]
+	for _, bar := range r.bars {
+		bar.FillColor = VercelBlue
+		bar.CornerRadius = 2
+	}
+
+	peak := r.peak()
+	r.maxText.Text = ""
+	if peak > 0 {
+		r.maxText.Text = formatTokens(int(peak))
+	}
+	r.first.Text, r.last.Text = "", ""
+	if len(r.chart.labels) > 0 {
+		r.first.Text = r.chart.labels[0]
+		r.last.Text = r.chart.labels[len(r.chart.labels)-1]
+	}
+	r.empty.Hidden = peak > 0
+
+	r.Layout(r.chart.Size())
+	for _, obj := range r.Objects() {
+		obj.Refresh()
+	}
+}
+
+func (r *barChartRenderer) Objects() []fyne.CanvasObject {
+	objects := []fyne.CanvasObject{r.baseline, r.maxText, r.first, r.last, r.empty}
+	for _, bar := range r.bars {
+		objects = append(objects, bar)
+	}
+	return objects
+}
+
+func (r *barChartRenderer) Destroy() {}
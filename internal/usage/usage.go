@@ -0,0 +1,228 @@
+// Package usage aggregates the token usage stored with replies by provider,
+// model, session and day, and estimates its cost from a price table.
+package usage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"axe-desktop/internal/storage"
+	"axe-desktop/pkg/models"
+)
+
+// PriceTable looks up model prices.
+type PriceTable []models.ModelPrice
+
+// Lookup returns the price for a model, preferring an exact match and then
+// the longest matching prefix.
+func (t PriceTable) Lookup(model string) (models.ModelPrice, bool) {
+	var best models.ModelPrice
+	found := false
+	for _, p := range t {
+		if p.Model == "" {
+			continue
+		}
+		if p.Model == model {
+			return p, true
+		}
+		if strings.HasPrefix(model, p.Model) && len(p.Model) > len(best.Model) {
+			best = p
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Cost estimates what the tokens of a record cost. It reports false when
+// the model has no price.
+func (t PriceTable) Cost(r models.UsageRecord) (float64, bool) {
+	p, ok := t.Lookup(r.Model)
+	if !ok {
+		return 0, false
+	}
+	return float64(r.PromptTokens)/1e6*p.InputPerMillion + float64(r.OutputTokens)/1e6*p.OutputPerMillion, true
+}
+
+// Totals sums usage under one key of a breakdown.
+type Totals struct {
+	Key          string
+	Label        string
+	Replies      int
+	PromptTokens int
+	OutputTokens int
+	Cost         float64
+	// Unpriced counts tokens from models missing in the price table; their
+	// cost is not included.
+	Unpriced int
+}
+
+func (t Totals) Tokens() int {
+	return t.PromptTokens + t.OutputTokens
+}
+
+func (t *Totals) add(r models.UsageRecord, prices PriceTable) {
+	t.Replies += r.Replies
+	t.PromptTokens += r.PromptTokens
+	t.OutputTokens += r.OutputTokens
+	if cost, ok := prices.Cost(r); ok {
+		t.Cost += cost
+	} else {
+		t.Unpriced += r.PromptTokens + r.OutputTokens
+	}
+}
+
+// Report is the usage since a point in time with its breakdowns. ByDay is
+// in date order with a row for every day in the range; the other breakdowns
+// are sorted by cost and then tokens, largest first.
+type Report struct {
+	Since      time.Time
+	Records    []models.UsageRecord
+	Prices     PriceTable
+	Total      Totals
+	ByProvider []Totals
+	ByModel    []Totals
+	BySession  []Totals
+	ByDay      []Totals
+}
+
+// Load reads the usage recorded since the given time and aggregates it.
+// providerName turns a provider ID into a display name.
+func Load(store *storage.Storage, since time.Time, prices PriceTable, providerName func(id string) string) (*Report, error) {
+	records, err := store.UsageRecords(since)
+	if err != nil {
+		return nil, err
+	}
+	return Build(records, since, time.Now(), prices, providerName), nil
+}
+
+// Build aggregates records into a report covering since to now.
+func Build(records []models.UsageRecord, since, now time.Time, prices PriceTable, providerName func(id string) string) *Report {
+	report := &Report{Since: since, Records: records, Prices: prices}
+
+	providers := map[string]*Totals{}
+	modelTotals := map[string]*Totals{}
+	sessions := map[string]*Totals{}
+	days := map[string]*Totals{}
+
+	group := func(m map[string]*Totals, key, label string) *Totals {
+		t, ok := m[key]
+		if !ok {
+			t = &Totals{Key: key, Label: label}
+			m[key] = t
+		}
+		return t
+	}
+
+	for _, r := range records {
+		report.Total.add(r, prices)
+		group(providers, r.ProviderID, providerName(r.ProviderID)).add(r, prices)
+		group(modelTotals, r.Model, modelLabel(r.Model)).add(r, prices)
+		group(sessions, r.SessionID, r.SessionTitle).add(r, prices)
+		group(days, r.Day, r.Day).add(r, prices)
+	}
+
+	report.ByProvider = sorted(providers)
+	report.ByModel = sorted(modelTotals)
+	report.BySession = sorted(sessions)
+
+	// Fill in days without usage so the chart has an even time axis. A range
+	// starting at the zero time begins at the first recorded day.
+	start := since
+	if start.IsZero() {
+		start = now
+		if len(records) > 0 {
+			if first, err := time.ParseInLocation(time.DateOnly, records[0].Day, time.Local); err == nil {
+				start = first
+			}
+		}
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	for day := start; !day.After(now); day = day.AddDate(0, 0, 1) {
+		key := day.Format(time.DateOnly)
+		report.ByDay = append(report.ByDay, *group(days, key, key))
+	}
+	return report
+}
+
+func sorted(m map[string]*Totals) []Totals {
+	out := make([]Totals, 0, len(m))
+	for _, t := range m {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Cost != out[j].Cost {
+			return out[i].Cost > out[j].Cost
+		}
+		if out[i].Tokens() != out[j].Tokens() {
+			return out[i].Tokens() > out[j].Tokens()
+		}
+		return out[i].Label < out[j].Label
+	})
+	return out
+}
+
+func modelLabel(model string) string {
+	if model == "" {
+		return "(provider default)"
+	}
+	return model
+}
+
+// Models lists the models that appear in the report, sorted by name.
+func (r *Report) Models() []string {
+	var names []string
+	for _, t := range r.ByModel {
+		if t.Key != "" {
+			names = append(names, t.Key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteCSV writes one row per day, provider, model and session. Cost is left
+// empty for models without a price.
+func WriteCSV(w io.Writer, report *Report, providerName func(id string) string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"day", "provider", "model", "session_id", "session", "replies",
+		"prompt_tokens", "output_tokens", "total_tokens", "cost_usd",
+	}); err != nil {
+		return err
+	}
+	for _, r := range report.Records {
+		cost := ""
+		if c, ok := report.Prices.Cost(r); ok {
+			cost = strconv.FormatFloat(c, 'f', 6, 64)
+		}
+		if err := cw.Write([]string{
+			r.Day,
+			providerName(r.ProviderID),
+			r.Model,
+			r.SessionID,
+			r.SessionTitle,
+			strconv.Itoa(r.Replies),
+			strconv.Itoa(r.PromptTokens),
+			strconv.Itoa(r.OutputTokens),
+			strconv.Itoa(r.PromptTokens + r.OutputTokens),
+			cost,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FormatCost formats a dollar amount with more precision for small sums.
+func FormatCost(cost float64) string {
+	if cost > 0 && cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
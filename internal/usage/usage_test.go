@@ -0,0 +1,106 @@
+package usage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"axe-desktop/pkg/models"
+)
+
+var testPrices = PriceTable{
+	{Model: "gpt-4o", InputPerMillion: 2.5, OutputPerMillion: 10},
+	{Model: "gpt-4o-mini", InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	{Model: "gemini", InputPerMillion: 1, OutputPerMillion: 1},
+	{Model: "", InputPerMillion: 100, OutputPerMillion: 100},
+}
+
+func TestPriceTableLookup(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+		found bool
+	}{
+		{"gpt-4o", "gpt-4o", true},
+		{"gpt-4o-mini", "gpt-4o-mini", true},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini", true},
+		{"gpt-4o-2024-08-06", "gpt-4o", true},
+		{"gemini-2.0-flash", "gemini", true},
+		{"claude-3", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		p, ok := testPrices.Lookup(tt.model)
+		if ok != tt.found || p.Model != tt.want {
+			t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.model, p.Model, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func day(s string) time.Time {
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func providerName(id string) string { return "name-" + id }
+
+func TestBuild(t *testing.T) {
+	records := []models.UsageRecord{
+		{Day: "2026-03-02", ProviderID: "p1", Model: "gpt-4o-mini", SessionID: "s1", SessionTitle: "One", Replies: 1, PromptTokens: 1_000_000},
+		{Day: "2026-03-04", ProviderID: "p1", Model: "gpt-4o", SessionID: "s2", SessionTitle: "Two", Replies: 2, PromptTokens: 1_000_000, OutputTokens: 1_000_000},
+		{Day: "2026-03-04", ProviderID: "p2", Model: "local", SessionID: "s1", SessionTitle: "One", Replies: 1, PromptTokens: 10, OutputTokens: 5},
+	}
+	now := day("2026-03-05").Add(15 * time.Hour)
+
+	report := Build(records, day("2026-03-01"), now, testPrices, providerName)
+
+	var days []string
+	for _, d := range report.ByDay {
+		days = append(days, d.Key)
+	}
+	wantDays := []string{"2026-03-01", "2026-03-02", "2026-03-03", "2026-03-04", "2026-03-05"}
+	if len(days) != len(wantDays) {
+		t.Fatalf("days = %v, want %v", days, wantDays)
+	}
+	for i := range days {
+		if days[i] != wantDays[i] {
+			t.Fatalf("days = %v, want %v", days, wantDays)
+		}
+	}
+	if report.ByDay[0].Replies != 0 || report.ByDay[2].Replies != 0 || report.ByDay[3].Replies != 3 {
+		t.Errorf("by day = %+v", report.ByDay)
+	}
+
+	if report.Total.Replies != 4 || report.Total.Unpriced != 15 {
+		t.Errorf("total = %+v", report.Total)
+	}
+	if want := 0.15 + 2.5 + 10; math.Abs(report.Total.Cost-want) > 1e-9 {
+		t.Errorf("total cost = %v, want %v", report.Total.Cost, want)
+	}
+	if len(report.ByProvider) != 2 || report.ByProvider[0].Label != "name-p1" {
+		t.Errorf("by provider = %+v", report.ByProvider)
+	}
+	if len(report.BySession) != 2 || report.BySession[0].Key != "s2" {
+		t.Errorf("by session = %+v, want s2 first by cost", report.BySession)
+	}
+}
+
+func TestBuildFromZeroTime(t *testing.T) {
+	records := []models.UsageRecord{
+		{Day: "2026-03-03", Model: "gpt-4o", SessionID: "s1", Replies: 1},
+	}
+	now := day("2026-03-04").Add(time.Hour)
+
+	report := Build(records, time.Time{}, now, testPrices, providerName)
+	if len(report.ByDay) != 2 || report.ByDay[0].Key != "2026-03-03" || report.ByDay[1].Key != "2026-03-04" {
+		t.Errorf("by day = %+v, want the first recorded day to today", report.ByDay)
+	}
+
+	report = Build(nil, time.Time{}, now, testPrices, providerName)
+	if len(report.ByDay) != 1 || report.ByDay[0].Key != "2026-03-04" {
+		t.Errorf("by day without records = %+v, want today only", report.ByDay)
+	}
+}
@@ -0,0 +1,145 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultCommandTimeout and MaxCommandTimeout bound how long a command
+	// may run.
+	DefaultCommandTimeout = 2 * time.Minute
+	MaxCommandTimeout     = 10 * time.Minute
+	// maxCommandOutput bounds the output kept from a command.
+	maxCommandOutput = 64 << 10
+)
+
+// CommandEnv lists the environment variables passed on to commands; anything
+// else, such as API keys, is left out.
+var CommandEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "LC_CTYPE", "TERM", "TZ",
+	"TMPDIR", "TEMP", "TMP",
+	"GOPATH", "GOROOT", "GOCACHE", "GOMODCACHE", "GOFLAGS", "CARGO_HOME", "RUSTUP_HOME", "JAVA_HOME", "NVM_DIR",
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "USERPROFILE", "APPDATA", "LOCALAPPDATA", "PROGRAMFILES",
+}
+
+// CommandResult is the outcome of a command that ran.
+type CommandResult struct {
+	ExitCode  int    `json:"exit_code"`
+	Output    string `json:"output"`
+	Truncated bool   `json:"truncated,omitempty"`
+	TimedOut  bool   `json:"timed_out,omitempty"`
+}
+
+// Run runs a shell command in dir, a folder of the workspace. stdout and
+// stderr are combined and passed to onOutput as they arrive, up to the output
+// limit. A command that exits with a non-zero status is not an error.
+func (w *Workspace) Run(ctx context.Context, command, dir string, timeout time.Duration, onOutput func(string)) (*CommandResult, error) {
+	if command == "" {
+		return nil, fmt.Errorf("command is empty")
+	}
+	full, err := w.Resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	timeout = min(timeout, MaxCommandTimeout)
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(runCtx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(runCtx, "sh", "-c", command)
+	}
+	cmd.Dir = full
+	cmd.Env = commandEnv()
+	// Children that keep the output open must not hold up a killed command.
+	cmd.WaitDelay = 2 * time.Second
+	killProcessGroup(cmd)
+
+	out := &cappedOutput{onOutput: onOutput}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err = cmd.Run()
+	result := &CommandResult{Output: out.String(), Truncated: out.truncated}
+	if runCtx.Err() != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result.TimedOut = true
+		result.ExitCode = -1
+		return result, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func commandEnv() []string {
+	var env []string
+	for _, name := range CommandEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// cappedOutput collects command output up to maxCommandOutput bytes.
+type cappedOutput struct {
+	mu        sync.Mutex
+	buf       []byte
+	truncated bool
+	onOutput  func(string)
+}
+
+func (o *cappedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.truncated {
+		return len(p), nil
+	}
+	chunk := p
+	if room := maxCommandOutput - len(o.buf); len(chunk) > room {
+		// Cut at the start of a rune so the output stays valid UTF-8.
+		for room > 0 && !utf8.RuneStart(chunk[room]) {
+			room--
+		}
+		chunk = chunk[:room]
+		o.truncated = true
+	}
+	o.buf = append(o.buf, chunk...)
+	if o.onOutput != nil {
+		text := string(chunk)
+		if o.truncated {
+			text += "\n[output truncated]\n"
+		}
+		o.onOutput(text)
+	}
+	return len(p), nil
+}
+
+func (o *cappedOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return string(o.buf)
+}
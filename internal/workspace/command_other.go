@@ -0,0 +1,8 @@
+//go:build !unix
+
+package workspace
+
+import "os/exec"
+
+// killProcessGroup keeps the default of killing only the command itself.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,103 @@
+//go:build unix
+
+package workspace
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func newTestWorkspace(t *testing.T) *Workspace {
+	t.Helper()
+	ws, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func TestRunExitCode(t *testing.T) {
+	ws := newTestWorkspace(t)
+	result, err := ws.Run(context.Background(), "echo out; echo err >&2; exit 3", "", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != 3 || result.Output != "out\nerr\n" || result.TimedOut {
+		t.Errorf("result = %+v", result)
+	}
+	if _, err := ws.Run(context.Background(), "true", "..", 0, nil); err == nil {
+		t.Error("ran in a folder outside the workspace")
+	}
+}
+
+func TestRunTimeoutKillsProcessGroup(t *testing.T) {
+	ws := newTestWorkspace(t)
+	start := time.Now()
+	// The background sleep keeps the output open; unless the whole process
+	// group is killed, Run waits for it until WaitDelay.
+	result, err := ws.Run(context.Background(), "sleep 30 & sleep 30", "", 200*time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TimedOut || result.ExitCode != -1 {
+		t.Errorf("result = %+v, want a timeout", result)
+	}
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Errorf("took %s to stop the command", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := ws.Run(ctx, "sleep 30", "", 0, nil); err != context.Canceled {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunTruncatesOutput(t *testing.T) {
+	ws := newTestWorkspace(t)
+	var streamed strings.Builder
+	// "é\n" is three bytes, so the limit falls inside a rune.
+	result, err := ws.Run(context.Background(), "yes é | head -c 200000", "", 0, func(s string) { streamed.WriteString(s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Truncated || result.ExitCode != 0 {
+		t.Errorf("result: truncated = %v, exit code = %d", result.Truncated, result.ExitCode)
+	}
+	if len(result.Output) > maxCommandOutput || len(result.Output) < maxCommandOutput-utf8.UTFMax {
+		t.Errorf("output is %d bytes, want just under %d", len(result.Output), maxCommandOutput)
+	}
+	if !utf8.ValidString(result.Output) {
+		t.Error("output was cut inside a rune")
+	}
+	if !strings.HasSuffix(streamed.String(), "\n[output truncated]\n") {
+		t.Error("streamed output does not say it was truncated")
+	}
+}
+
+func TestRunEnv(t *testing.T) {
+	ws := newTestWorkspace(t)
+	t.Setenv("AXE_TEST_API_KEY", "secret")
+	t.Setenv("LANG", "C.UTF-8")
+	result, err := ws.Run(context.Background(), "env", "", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shell sets these itself.
+	shellVars := []string{"PWD", "OLDPWD", "SHLVL", "_"}
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(result.Output), "\n") {
+		name, _, _ := strings.Cut(line, "=")
+		names = append(names, name)
+		if !slices.Contains(CommandEnv, name) && !slices.Contains(shellVars, name) {
+			t.Errorf("%s was passed to the command", name)
+		}
+	}
+	if !slices.Contains(names, "PATH") || !slices.Contains(names, "LANG") {
+		t.Errorf("allowed variables are missing: %v", names)
+	}
+}
@@ -0,0 +1,191 @@
+package workspace
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// treeDepth and maxTreeLines bound the file tree in the context.
+	treeDepth    = 3
+	maxTreeLines = 300
+	// maxContextFileBytes bounds each README or AGENTS.md in the context.
+	maxContextFileBytes = 16 << 10
+	// maxAgentsFiles bounds how many AGENTS.md files are included.
+	maxAgentsFiles = 5
+)
+
+// readmeNames are tried in order for the project README.
+var readmeNames = []string{"README.md", "README", "README.txt", "README.rst", "readme.md", "Readme.md"}
+
+// context describes the workspace for the agent's instruction: a summary of
+// the file tree, the README and the content of any AGENTS.md files. The
+// folders and files it reads are noted in read.
+func (w *Workspace) context(read modTimes) string {
+	var b strings.Builder
+	b.WriteString("Files in the workspace:\n```\n")
+	var agents []string
+	b.WriteString(w.tree(&agents, read))
+	b.WriteString("```")
+
+	for _, name := range readmeNames {
+		if content, ok := w.readContextFile(filepath.Join(w.root, name), read); ok {
+			fmt.Fprintf(&b, "\n\nThe project README (%s):\n\n%s", name, content)
+			break
+		}
+	}
+	for _, path := range agents {
+		if content, ok := w.readContextFile(path, read); ok {
+			fmt.Fprintf(&b, "\n\nInstructions from %s; follow them when working on files under its folder:\n\n%s", w.Rel(path), content)
+		}
+	}
+	return b.String()
+}
+
+// ContextCache builds the context that describes a workspace to the agent
+// and keeps it until one of the folders or files it was built from changes,
+// so it is not rebuilt for every request. The zero value is ready to use.
+type ContextCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedContext
+}
+
+type cachedContext struct {
+	text string
+	read modTimes
+}
+
+// Context returns the context of w, building it again if it is out of date.
+func (c *ContextCache) Context(w *Workspace) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[w.root]; ok && !entry.read.changed() {
+		return entry.text
+	}
+	read := modTimes{}
+	text := w.context(read)
+	if c.entries == nil {
+		c.entries = make(map[string]cachedContext)
+	}
+	c.entries[w.root] = cachedContext{text: text, read: read}
+	return text
+}
+
+// modTimes records the modification times of the folders and files a
+// context was built from, taken before each is read.
+type modTimes map[string]time.Time
+
+func (m modTimes) note(path string) {
+	if info, err := os.Stat(path); err == nil {
+		m[path] = info.ModTime()
+	}
+}
+
+func (m modTimes) changed() bool {
+	for path, modTime := range m {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// tree lists the workspace a few folders deep, folders first, and collects
+// the AGENTS.md files it passes.
+func (w *Workspace) tree(agents *[]string, read modTimes) string {
+	var b strings.Builder
+	lines := 0
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		read.note(dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			if entries[i].IsDir() != entries[j].IsDir() {
+				return entries[i].IsDir()
+			}
+			return entries[i].Name() < entries[j].Name()
+		})
+		indent := strings.Repeat("  ", depth)
+		for i, e := range entries {
+			if e.Name() == "AGENTS.md" && e.Type().IsRegular() && len(*agents) < maxAgentsFiles {
+				*agents = append(*agents, filepath.Join(dir, e.Name()))
+			}
+			if lines == maxTreeLines {
+				fmt.Fprintf(&b, "%s... (%d more)\n", indent, len(entries)-i)
+				return
+			}
+			lines++
+			if !e.IsDir() {
+				fmt.Fprintf(&b, "%s%s\n", indent, e.Name())
+				continue
+			}
+			fmt.Fprintf(&b, "%s%s/\n", indent, e.Name())
+			if skipDirs[e.Name()] {
+				continue
+			}
+			if depth+1 < treeDepth {
+				walk(filepath.Join(dir, e.Name()), depth+1)
+			} else {
+				sub := filepath.Join(dir, e.Name())
+				read.note(sub)
+				if hasEntries(sub) {
+					fmt.Fprintf(&b, "%s  ...\n", indent)
+				}
+			}
+		}
+	}
+	walk(w.root, 0)
+	if lines == 0 {
+		return "(empty)\n"
+	}
+	return b.String()
+}
+
+func hasEntries(dir string) bool {
+	f, err := os.Open(dir)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	names, _ := f.Readdirnames(1)
+	return len(names) > 0
+}
+
+// readContextFile reads a text file for the context, cutting it short if it
+// is long. Links that leave the workspace are not followed.
+func (w *Workspace) readContextFile(path string, read modTimes) (string, bool) {
+	full, err := w.Resolve(path)
+	if err != nil {
+		return "", false
+	}
+	read.note(full)
+	info, err := os.Stat(full)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxContextFileBytes))
+	if err != nil || !isText(data) {
+		return "", false
+	}
+	content := strings.TrimSpace(strings.ToValidUTF8(string(data), ""))
+	if info.Size() > int64(len(data)) {
+		content += "\n\n(cut short)"
+	}
+	return content, content != ""
+}
@@ -0,0 +1,66 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextCache(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("README.md", "# Demo")
+	write("pkg/AGENTS.md", "Run go test.")
+	ws, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cache ContextCache
+	text := cache.Context(ws)
+	for _, want := range []string{"README.md", "pkg/\n  AGENTS.md", "# Demo", "Instructions from pkg/AGENTS.md", "Run go test."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("context is missing %q:\n%s", want, text)
+		}
+	}
+
+	// A change that keeps the modification time is not seen, showing the
+	// cached context is used.
+	readme := filepath.Join(root, "README.md")
+	info, _ := os.Stat(readme)
+	write("README.md", "# Edit")
+	if err := os.Chtimes(readme, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.Context(ws); got != text {
+		t.Errorf("context was rebuilt without a change:\n%s", got)
+	}
+
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(readme, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.Context(ws); !strings.Contains(got, "# Edit") {
+		t.Errorf("edited README is missing:\n%s", got)
+	}
+
+	write("pkg/new.go", "package pkg")
+	dir := filepath.Join(root, "pkg")
+	if err := os.Chtimes(dir, later.Add(time.Second), later.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.Context(ws); !strings.Contains(got, "new.go") {
+		t.Errorf("new file is missing from the tree:\n%s", got)
+	}
+}
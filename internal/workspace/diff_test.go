@@ -0,0 +1,75 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	long := strings.Repeat("line\n", 20)
+
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "equal",
+			old:  "a\n",
+			new:  "a\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "a\n",
+			want: "--- /dev/null\n+++ b/f\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "first\n" + long + "last\n",
+			new:  "FIRST\n" + long + "LAST\n",
+			want: "--- a/f\n+++ b/f\n" +
+				"@@ -1,4 +1,4 @@\n-first\n+FIRST\n line\n line\n line\n" +
+				"@@ -19,4 +19,4 @@\n line\n line\n line\n-last\n+LAST\n",
+		},
+		{
+			name: "newline added at the end",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "newline removed at the end",
+			old:  "a\nb\n",
+			new:  "a\nb",
+			want: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff("f", tt.old, tt.new)
+			if got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+			// Every diff applies back to the old content.
+			applied, err := ApplyPatch(tt.old, got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if applied != tt.new {
+				t.Errorf("applying the diff gave %q, want %q", applied, tt.new)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package workspace
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+type hunk struct {
+	oldStart int
+	old      []string
+	new      []string
+	// oldNoEOL and newNoEOL are set when the hunk's last old or new line
+	// is marked as having no newline at the end of the file.
+	oldNoEOL, newNoEOL bool
+}
+
+// ApplyPatch applies the hunks of a unified diff for a single file to
+// content. Each hunk is matched by its context and removed lines, searching
+// outward from the line number in its header so patches with shifted line
+// numbers still apply.
+func ApplyPatch(content, patch string) (string, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return "", err
+	}
+	lines, trailing := splitLines(content)
+	if content == "" {
+		trailing = true
+	}
+
+	offset := 0
+	for n, h := range hunks {
+		at := find(lines, h.old, h.oldStart-1+offset)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d does not match the file; read the file again and regenerate the patch", n+1)
+		}
+		updated := make([]string, 0, len(lines)-len(h.old)+len(h.new))
+		updated = append(updated, lines[:at]...)
+		updated = append(updated, h.new...)
+		updated = append(updated, lines[at+len(h.old):]...)
+		lines = updated
+		offset = at + len(h.new) - (h.oldStart - 1) - len(h.old)
+		if at+len(h.new) == len(lines) {
+			if h.newNoEOL {
+				trailing = false
+			} else if h.oldNoEOL {
+				trailing = true
+			}
+		}
+	}
+	return joinLines(lines, trailing), nil
+}
+
+func parsePatch(patch string) ([]hunk, error) {
+	patch = strings.TrimSuffix(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	lines := strings.Split(patch, "\n")
+
+	var hunks []hunk
+	var cur *hunk
+	// The line counts in the hunk header say how much of what follows is
+	// part of the hunk, so removed and added lines that look like file
+	// headers are not mistaken for them.
+	oldLeft, newLeft := 0, 0
+	var last byte
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			start, _ := strconv.Atoi(m[1])
+			hunks = append(hunks, hunk{oldStart: max(start, 1)})
+			cur = &hunks[len(hunks)-1]
+			oldLeft, newLeft = hunkCount(m[2]), hunkCount(m[3])
+			continue
+		}
+		// A "---" line followed by "+++" outside a hunk is a file header.
+		if oldLeft <= 0 && newLeft <= 0 && strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			if len(hunks) > 0 {
+				return nil, fmt.Errorf("patch changes more than one file; send one patch per file")
+			}
+			i++
+			continue
+		}
+		if cur == nil {
+			// Any text before the first hunk.
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "-"):
+			cur.old = append(cur.old, line[1:])
+			oldLeft--
+		case strings.HasPrefix(line, "+"):
+			cur.new = append(cur.new, line[1:])
+			newLeft--
+		case strings.HasPrefix(line, " "):
+			cur.old = append(cur.old, line[1:])
+			cur.new = append(cur.new, line[1:])
+			oldLeft--
+			newLeft--
+		case line == "":
+			// Blank context lines often lose their leading space.
+			cur.old = append(cur.old, "")
+			cur.new = append(cur.new, "")
+			oldLeft--
+			newLeft--
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" after the line it applies to.
+			cur.oldNoEOL = cur.oldNoEOL || last == '-' || last == ' '
+			cur.newNoEOL = cur.newNoEOL || last == '+' || last == ' '
+		default:
+			return nil, fmt.Errorf("unexpected line in patch: %q", line)
+		}
+		if line == "" {
+			last = ' '
+		} else {
+			last = line[0]
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch has no hunks; use the unified diff format with @@ headers")
+	}
+	return hunks, nil
+}
+
+// hunkCount parses a line count from a hunk header, which defaults to 1.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// find returns where block occurs in lines, preferring the position closest
+// to near, or -1.
+func find(lines, block []string, near int) int {
+	if len(block) == 0 {
+		return min(max(near, 0), len(lines))
+	}
+	for d := 0; d <= len(lines); d++ {
+		for _, at := range []int{near - d, near + d} {
+			if at < 0 || at+len(block) > len(lines) {
+				continue
+			}
+			if matchAt(lines, block, at) {
+				return at
+			}
+		}
+	}
+	return -1
+}
+
+func matchAt(lines, block []string, at int) bool {
+	for i, line := range block {
+		if lines[at+i] != line {
+			return false
+		}
+	}
+	return true
+}
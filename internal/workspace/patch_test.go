@@ -0,0 +1,90 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const file = "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+
+	tests := []struct {
+		name    string
+		content string
+		patch   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "single hunk",
+			content: file,
+			patch:   "--- a/f\n+++ b/f\n@@ -2,3 +2,3 @@\n two\n-three\n+THREE\n four\n",
+			want:    strings.Replace(file, "three", "THREE", 1),
+		},
+		{
+			name:    "multiple hunks",
+			content: file,
+			patch:   "@@ -1,2 +1,3 @@\n one\n+one and a half\n two\n@@ -9,2 +10,1 @@\n nine\n-ten\n",
+			want:    "one\none and a half\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\n",
+		},
+		{
+			name:    "shifted line numbers",
+			content: "header\nheader\n" + file,
+			patch:   "@@ -5,1 +5,1 @@\n-five\n+FIVE\n",
+			want:    "header\nheader\n" + strings.Replace(file, "five", "FIVE", 1),
+		},
+		{
+			name:    "context mismatch",
+			content: file,
+			patch:   "@@ -2,2 +2,2 @@\n two\n-tree\n+THREE\n",
+			wantErr: "hunk 1 does not match",
+		},
+		{
+			name:    "removed and added lines that look like headers",
+			content: "-- comment\nSELECT 1;\n",
+			patch:   "--- a/q.sql\n+++ b/q.sql\n@@ -1,2 +1,2 @@\n--- comment\n+++ x\n SELECT 1;\n",
+			want:    "++ x\nSELECT 1;\n",
+		},
+		{
+			name:    "second file",
+			content: file,
+			patch:   "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-one\n+ONE\n--- a/g\n+++ b/g\n@@ -1 +1 @@\n-x\n+y\n",
+			wantErr: "more than one file",
+		},
+		{
+			name:    "no hunks",
+			content: file,
+			patch:   "--- a/f\n+++ b/f\n",
+			wantErr: "no hunks",
+		},
+		{
+			name:    "new file",
+			content: "",
+			patch:   "--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+			want:    "a\nb\n",
+		},
+		{
+			name:    "drop the final newline",
+			content: "a\nb\n",
+			patch:   "@@ -2 +2 @@\n-b\n+b\n\\ No newline at end of file\n",
+			want:    "a\nb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyPatch(tt.content, tt.patch)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
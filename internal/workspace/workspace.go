@@ -0,0 +1,375 @@
+// Package workspace implements file operations confined to a project
+// directory, used by the agent's filesystem tools.
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxReadLines and maxReadBytes bound how much of a file one read returns.
+	MaxReadLines = 2000
+	maxReadBytes = 256 << 10
+	// maxListEntries bounds a directory listing.
+	maxListEntries = 500
+	// maxSearchFileSize skips large files when searching.
+	maxSearchFileSize = 1 << 20
+	// MaxSearchMatches bounds the matches one search returns.
+	MaxSearchMatches = 100
+	// maxWriteBytes bounds the size of a written file.
+	maxWriteBytes = 4 << 20
+)
+
+// ErrOutsideRoot is returned for paths that leave the workspace.
+var ErrOutsideRoot = errors.New("path is outside the workspace")
+
+// skipDirs are not descended into when searching.
+var skipDirs = map[string]bool{".git": true, ".hg": true, ".svn": true, "node_modules": true}
+
+// Workspace is a directory the agent may read and write.
+type Workspace struct {
+	root string
+}
+
+// New opens the workspace rooted at dir.
+func New(dir string) (*Workspace, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", dir)
+	}
+	return &Workspace{root: root}, nil
+}
+
+// Root is the workspace directory.
+func (w *Workspace) Root() string {
+	return w.root
+}
+
+// Resolve turns a path relative to the root, or an absolute path inside it,
+// into an absolute path. Symbolic links are followed so they cannot point out
+// of the workspace.
+func (w *Workspace) Resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	full := path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(w.root, full)
+	}
+	full = filepath.Clean(full)
+	if !w.contains(full) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+	}
+
+	// Resolve links in the part of the path that exists; the rest is
+	// created inside the resolved directory.
+	existing, rest := full, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			full = filepath.Join(resolved, rest)
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	if !w.contains(full) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+	}
+	return full, nil
+}
+
+func (w *Workspace) contains(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// Rel returns path relative to the root with forward slashes, for display.
+func (w *Workspace) Rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// FileContent is a range of lines read from a file.
+type FileContent struct {
+	Path       string `json:"path"`
+	Content    string `json:"content"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	TotalLines int    `json:"total_lines"`
+	Truncated  bool   `json:"truncated,omitempty"`
+}
+
+// ReadFile reads up to limit lines starting at the 1-based line offset.
+func (w *Workspace) ReadFile(path string, offset, limit int) (*FileContent, error) {
+	full, err := w.Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	if !isText(data) {
+		return nil, fmt.Errorf("%s is not a text file", path)
+	}
+
+	lines, _ := splitLines(string(data))
+	if offset < 1 {
+		offset = 1
+	}
+	if limit <= 0 || limit > MaxReadLines {
+		limit = MaxReadLines
+	}
+	result := &FileContent{Path: w.Rel(full), StartLine: offset, TotalLines: len(lines)}
+	if offset > len(lines) {
+		result.EndLine = offset - 1
+		return result, nil
+	}
+
+	end := min(offset-1+limit, len(lines))
+	var b strings.Builder
+	for i := offset - 1; i < end; i++ {
+		if b.Len()+len(lines[i]) > maxReadBytes {
+			end = i
+			break
+		}
+		b.WriteString(lines[i])
+		b.WriteByte('\n')
+	}
+	result.Content = b.String()
+	result.EndLine = end
+	result.Truncated = end < len(lines)
+	return result, nil
+}
+
+// Entry is an item in a directory listing.
+type Entry struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"is_dir,omitempty"`
+	Size  int64  `json:"size,omitempty"`
+}
+
+// ListDir lists a directory, folders first. It reports whether the listing
+// was cut short.
+func (w *Workspace) ListDir(path string) ([]Entry, bool, error) {
+	full, err := w.Resolve(path)
+	if err != nil {
+		return nil, false, err
+	}
+	dirEntries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		e := Entry{Name: de.Name(), IsDir: de.IsDir()}
+		if !e.IsDir {
+			if info, err := de.Info(); err == nil {
+				e.Size = info.Size()
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	if len(entries) > maxListEntries {
+		return entries[:maxListEntries], true, nil
+	}
+	return entries, false, nil
+}
+
+// Match is a line matching a search.
+type Match struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// Search finds lines matching a regular expression in the text files under
+// path. glob, if set, filters file names. It reports whether more matches
+// were found than returned.
+func (w *Workspace) Search(pattern, path, glob string) ([]Match, bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if glob != "" {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, false, fmt.Errorf("invalid glob: %w", err)
+		}
+	}
+	start, err := w.Resolve(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := os.Stat(start); err != nil {
+		return nil, false, err
+	}
+
+	var matches []Match
+	errLimit := errors.New("limit reached")
+	err = filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip unreadable entries rather than failing the search.
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if p != start && skipDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if glob != "" {
+			if ok, _ := filepath.Match(glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxSearchFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil || !isText(data) {
+			return nil
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64<<10), maxSearchFileSize)
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			if !re.MatchString(text) {
+				continue
+			}
+			if len(matches) == MaxSearchMatches {
+				return errLimit
+			}
+			if len(text) > 300 {
+				text = strings.ToValidUTF8(text[:300], "") + "..."
+			}
+			matches = append(matches, Match{Path: w.Rel(p), Line: line, Text: text})
+		}
+		return nil
+	})
+	if errors.Is(err, errLimit) {
+		return matches, true, nil
+	}
+	return matches, false, err
+}
+
+// Current returns the content of a file, or "" if it does not exist yet.
+func (w *Workspace) Current(path string) (string, bool, error) {
+	full, err := w.Resolve(path)
+	if err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(full)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if !isText(data) {
+		return "", true, fmt.Errorf("%s is not a text file", path)
+	}
+	return string(data), true, nil
+}
+
+// WriteFile replaces a file's content, creating it and its parent folders if
+// needed.
+func (w *Workspace) WriteFile(path, content string) error {
+	if len(content) > maxWriteBytes {
+		return fmt.Errorf("content is larger than %d MB", maxWriteBytes>>20)
+	}
+	full, err := w.Resolve(path)
+	if err != nil {
+		return err
+	}
+	if full == w.root {
+		return fmt.Errorf("%s is the workspace folder", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(full); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a folder", path)
+		}
+		mode = info.Mode().Perm()
+	}
+	return os.WriteFile(full, []byte(content), mode)
+}
+
+// isText treats data as text when its start has no NUL bytes, as binary
+// formats almost always do.
+func isText(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	return bytes.IndexByte(sample, 0) == -1
+}
+
+// splitLines splits text into lines and reports whether it ended with a
+// newline.
+func splitLines(text string) ([]string, bool) {
+	if text == "" {
+		return nil, false
+	}
+	trailing := strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+	return strings.Split(text, "\n"), trailing
+}
+
+func joinLines(lines []string, trailing bool) string {
+	text := strings.Join(lines, "\n")
+	if trailing && len(lines) > 0 {
+		text += "\n"
+	}
+	return text
+}
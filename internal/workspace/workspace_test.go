@@ -0,0 +1,72 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	symlinks := true
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		symlinks = false
+	}
+	if symlinks {
+		if err := os.Symlink(filepath.Join(root, "sub"), filepath.Join(root, "inner")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ws, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The root may itself be reached through a link, such as /tmp on macOS.
+	real := ws.Root()
+	outsideReal, _ := filepath.EvalSymlinks(outside)
+
+	tests := []struct {
+		name    string
+		path    string
+		symlink bool
+		want    string // relative to the root; ignored when wantErr
+		wantErr bool
+	}{
+		{name: "empty is the root", path: "", want: "."},
+		{name: "relative", path: "sub/file.go", want: "sub/file.go"},
+		{name: "missing folders", path: "new/dir/file.go", want: "new/dir/file.go"},
+		{name: "dot dot inside", path: "sub/../file.go", want: "file.go"},
+		{name: "absolute inside", path: filepath.Join(real, "sub"), want: "sub"},
+		{name: "dot dot", path: "../file.go", wantErr: true},
+		{name: "dot dot through a folder", path: "sub/../../file.go", wantErr: true},
+		{name: "absolute outside", path: filepath.Join(outsideReal, "file.go"), wantErr: true},
+		{name: "link inside", path: "inner/file.go", symlink: true, want: "sub/file.go"},
+		{name: "link escape", path: "escape", symlink: true, wantErr: true},
+		{name: "link escape to a new file", path: "escape/new/file.go", symlink: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.symlink && !symlinks {
+				t.Skip("symbolic links are not supported here")
+			}
+			got, err := ws.Resolve(tt.path)
+			if tt.wantErr {
+				if !errors.Is(err, ErrOutsideRoot) {
+					t.Fatalf("Resolve(%q) = %q, %v; want ErrOutsideRoot", tt.path, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(real, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, want)
+			}
+		})
+	}
+}
@@ -16,8 +16,23 @@ type Provider struct {
 	Name    string       `json:"name"`
 	Type    ProviderType `json:"type"`
 	APIKey  string       `json:"api_key"`
+	BaseURL string       `json:"base_url,omitempty"`
 	Model   string       `json:"model"`
 	Enabled bool         `json:"enabled"`
+	// ContextWindow is the model's context size in tokens. Zero means
+	// DefaultContextWindow.
+	ContextWindow int `json:"context_window,omitempty"`
+}
+
+// DefaultContextWindow is assumed for providers that do not set one.
+const DefaultContextWindow = 128000
+
+// ContextLimit returns the provider's context window in tokens.
+func (p *Provider) ContextLimit() int {
+	if p.ContextWindow > 0 {
+		return p.ContextWindow
+	}
+	return DefaultContextWindow
 }
 
 type MCPServerType string
@@ -28,15 +43,30 @@ const (
 )
 
 type MCPServer struct {
-	ID      string        `json:"id"`
-	Name    string        `json:"name"`
-	Type    MCPServerType `json:"type"`
-	URL     string        `json:"url,omitempty"`
-	Command string        `json:"command,omitempty"`
-	Args    []string      `json:"args,omitempty"`
-	Enabled bool          `json:"enabled"`
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Type    MCPServerType     `json:"type"`
+	URL     string            `json:"url,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Enabled bool              `json:"enabled"`
 }
 
+// ToolPolicy decides whether a tool may run without asking the user.
+type ToolPolicy string
+
+const (
+	ToolPolicyAllow ToolPolicy = "allow"
+	ToolPolicyAsk   ToolPolicy = "ask"
+	ToolPolicyDeny  ToolPolicy = "deny"
+)
+
+// DefaultToolPolicy applies to tools without a policy of their own.
+const DefaultToolPolicy = ToolPolicyAsk
+
+const DefaultSystemPrompt = "You are a helpful AI assistant. Search the web when needed."
+
 type MessageRole string
 
 const (
@@ -62,16 +92,22 @@ type User struct {
 }
 
 type Session struct {
-	ID           string     `db:"id" json:"id"`
-	UserID       string     `db:"user_id" json:"user_id"`
-	Title        string     `db:"title" json:"title"`
-	Model        string     `db:"model" json:"model"`
-	ProviderID   string     `db:"provider_id" json:"provider_id"`
-	SystemPrompt string     `db:"system_prompt" json:"system_prompt"`
-	Summary      *string    `db:"summary" json:"summary,omitempty"`
-	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
-	ArchivedAt   *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+	ID           string  `db:"id" json:"id"`
+	UserID       string  `db:"user_id" json:"user_id"`
+	Title        string  `db:"title" json:"title"`
+	Model        string  `db:"model" json:"model"`
+	ProviderID   string  `db:"provider_id" json:"provider_id"`
+	SystemPrompt string  `db:"system_prompt" json:"system_prompt"`
+	Summary      *string `db:"summary" json:"summary,omitempty"`
+	// ContextSummary replaces the messages up to SummaryUntil when the
+	// conversation is sent to the model.
+	ContextSummary *string    `db:"context_summary" json:"context_summary,omitempty"`
+	SummaryUntil   *time.Time `db:"summary_until" json:"summary_until,omitempty"`
+	SourceID       string     `db:"source_id" json:"source_id,omitempty"`
+	Workspace      string     `db:"workspace" json:"workspace,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+	ArchivedAt     *time.Time `db:"archived_at" json:"archived_at,omitempty"`
 }
 
 type Message struct {
@@ -86,16 +122,34 @@ type Message struct {
 }
 
 type ToolCall struct {
-	ID        string         `db:"id" json:"id"`
-	SessionID string         `db:"session_id" json:"session_id"`
-	MessageID string         `db:"message_id" json:"message_id"`
-	ToolName  string         `db:"tool_name" json:"tool_name"`
-	Args      map[string]any `db:"args_json" json:"args"`
-	Result    map[string]any `db:"result_json" json:"result,omitempty"`
-	Error     *string        `db:"error" json:"error,omitempty"`
-	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+	ID         string         `db:"id" json:"id"`
+	SessionID  string         `db:"session_id" json:"session_id"`
+	MessageID  string         `db:"message_id" json:"message_id"`
+	ToolName   string         `db:"tool_name" json:"tool_name"`
+	Args       map[string]any `db:"args_json" json:"args"`
+	Result     map[string]any `db:"result_json" json:"result,omitempty"`
+	Error      *string        `db:"error" json:"error,omitempty"`
+	DurationMs *int64         `db:"duration_ms" json:"duration_ms,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// SearchResult is a message or tool call matching a full-text search. For
+// tool calls, MessageID is the assistant message that made the call.
+type SearchResult struct {
+	MessageID    string      `json:"message_id"`
+	SessionID    string      `json:"session_id"`
+	SessionTitle string      `json:"session_title"`
+	Role         MessageRole `json:"role"`
+	Snippet      string      `json:"snippet"`
+	Rank         float64     `json:"rank"`
+	CreatedAt    time.Time   `json:"created_at"`
 }
 
+const (
+	AttachmentImage = "image"
+	AttachmentFile  = "file"
+)
+
 type Attachment struct {
 	ID        string         `db:"id" json:"id"`
 	SessionID string         `db:"session_id" json:"session_id"`
@@ -110,3 +164,37 @@ type Settings struct {
 	Key   string `db:"key" json:"key"`
 	Value any    `db:"value_json" json:"value"`
 }
+
+// TokenUsage totals the tokens the provider reported for a session.
+// ContextTokens is the size of the latest request and reply, i.e. how much of
+// the context window the conversation occupies.
+type TokenUsage struct {
+	PromptTokens  int `json:"prompt_tokens"`
+	OutputTokens  int `json:"output_tokens"`
+	ContextTokens int `json:"context_tokens"`
+}
+
+func (u TokenUsage) Total() int {
+	return u.PromptTokens + u.OutputTokens
+}
+
+// ModelPrice is what a model costs in US dollars per million tokens. Model
+// matches a model name exactly or as a prefix, so "gpt-4o" also prices
+// dated snapshots such as "gpt-4o-2024-08-06".
+type ModelPrice struct {
+	Model            string  `json:"model"`
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// UsageRecord is the token usage of one session on one day with one model.
+type UsageRecord struct {
+	Day          string `json:"day"`
+	ProviderID   string `json:"provider_id"`
+	Model        string `json:"model"`
+	SessionID    string `json:"session_id"`
+	SessionTitle string `json:"session_title"`
+	Replies      int    `json:"replies"`
+	PromptTokens int    `json:"prompt_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+}